@@ -9,6 +9,8 @@ import (
 	"timetracker/internal/database"
 	"timetracker/internal/handlers"
 	"timetracker/internal/middleware"
+	"timetracker/internal/scheduler"
+	"timetracker/internal/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -26,6 +28,15 @@ func main() {
 	}
 	defer sqlDB.Close()
 
+	// Initialize attachment storage
+	storage.Init()
+
+	// Background jobs
+	scheduler.Start(config.AppConfig.SchedulerInterval,
+		scheduler.Job{Name: "recurring tasks", Run: handlers.SpawnDueRecurringTasks},
+		scheduler.Job{Name: "overdue invoices", Run: handlers.FlagOverdueInvoices},
+	)
+
 	// Initialize router
 	r := gin.Default()
 
@@ -33,8 +44,8 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "X-Workspace-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Next-Cursor"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -51,36 +62,166 @@ func main() {
 
 		// Protected routes
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(), middleware.WorkspaceMiddleware())
 		{
+			// Current user
+			protected.GET("/me", handlers.GetCurrentUser)
+			protected.PUT("/me", handlers.UpdateCurrentUser)
+
+			// Workspaces
+			protected.GET("/workspaces", handlers.GetWorkspaces)
+			protected.GET("/workspaces/:id", handlers.GetWorkspace)
+			protected.POST("/workspaces", handlers.CreateWorkspace)
+			protected.PUT("/workspaces/:id", handlers.UpdateWorkspace)
+			protected.DELETE("/workspaces/:id", handlers.DeleteWorkspace)
+			protected.POST("/workspaces/:id/switch", handlers.SwitchWorkspace)
+			protected.GET("/workspaces/:id/members", handlers.GetWorkspaceMembers)
+			protected.POST("/workspaces/:id/members", handlers.AddWorkspaceMember)
+			protected.PUT("/workspaces/:id/members/:userId", handlers.UpdateWorkspaceMember)
+			protected.DELETE("/workspaces/:id/members/:userId", handlers.RemoveWorkspaceMember)
+			protected.GET("/workspaces/:id/cost-rates", handlers.GetWorkspaceCostRates)
+			protected.PUT("/workspaces/:id/members/:userId/cost-rate", handlers.UpdateWorkspaceCostRate)
+
+			// Clients
+			protected.GET("/clients", handlers.GetClients)
+			protected.GET("/clients/:id", handlers.GetClient)
+			protected.POST("/clients", handlers.CreateClient)
+			protected.PUT("/clients/:id", handlers.UpdateClient)
+			protected.DELETE("/clients/:id", handlers.DeleteClient)
+
 			// Projects
 			protected.GET("/projects", handlers.GetProjects)
 			protected.GET("/projects/:id", handlers.GetProject)
 			protected.POST("/projects", handlers.CreateProject)
 			protected.PUT("/projects/:id", handlers.UpdateProject)
 			protected.DELETE("/projects/:id", handlers.DeleteProject)
+			protected.POST("/projects/:id/duplicate", handlers.DuplicateProject)
+			protected.POST("/projects/:id/template", handlers.SaveProjectAsTemplate)
+			protected.GET("/projects/:id/board", handlers.GetProjectBoard)
+			protected.GET("/projects/:id/attachments", handlers.GetProjectAttachments)
+			protected.POST("/projects/:id/attachments", handlers.UploadProjectAttachment)
+			protected.POST("/projects/:id/tasks/import", handlers.ImportTasks)
+
+			// Project members and invitations
+			protected.GET("/projects/:id/members", handlers.GetProjectMembers)
+			protected.PUT("/projects/:id/members/:userId", handlers.UpdateProjectMember)
+			protected.DELETE("/projects/:id/members/:userId", handlers.RemoveProjectMember)
+			protected.GET("/projects/:id/cost-rates", handlers.GetProjectCostRates)
+			protected.GET("/projects/:id/invitations", handlers.GetProjectInvitations)
+			protected.POST("/projects/:id/invitations", handlers.CreateProjectInvitation)
+			protected.DELETE("/projects/:id/invitations/:invitationId", handlers.DeleteProjectInvitation)
+			protected.GET("/invitations", handlers.GetMyInvitations)
+			protected.POST("/invitations/:token/accept", handlers.AcceptInvitation)
+
+			// Project templates
+			protected.GET("/templates", handlers.GetTemplates)
+			protected.GET("/templates/:id", handlers.GetTemplate)
+			protected.POST("/templates/:id/projects", handlers.CreateProjectFromTemplate)
+			protected.DELETE("/templates/:id", handlers.DeleteTemplate)
+
+			// Custom fields
+			protected.GET("/custom-fields", handlers.GetCustomFields)
+			protected.POST("/custom-fields", handlers.CreateCustomField)
+			protected.PUT("/custom-fields/:id", handlers.UpdateCustomField)
+			protected.DELETE("/custom-fields/:id", handlers.DeleteCustomField)
+
+			// Issue trackers
+			protected.GET("/issue-trackers", handlers.GetIssueTrackers)
+			protected.PUT("/issue-trackers/:provider", handlers.SaveIssueTracker)
+			protected.DELETE("/issue-trackers/:provider", handlers.DeleteIssueTracker)
+
+			// Tags
+			protected.GET("/tags", handlers.GetTags)
+			protected.POST("/tags", handlers.CreateTag)
+			protected.PUT("/tags/:id", handlers.UpdateTag)
+			protected.DELETE("/tags/:id", handlers.DeleteTag)
+			protected.POST("/tags/:id/merge", handlers.MergeTag)
 
 			// Time entries
 			protected.GET("/time-entries", handlers.GetTimeEntries)
+			protected.GET("/time-entries/export", handlers.ExportTimeEntries)
 			protected.GET("/time-entries/:id", handlers.GetTimeEntry)
 			protected.POST("/time-entries", handlers.CreateTimeEntry)
+			protected.POST("/time-entries/start", handlers.StartTimeEntry)
+			protected.POST("/time-entries/:id/stop", handlers.StopTimeEntry)
 			protected.PUT("/time-entries/:id", handlers.UpdateTimeEntry)
 			protected.DELETE("/time-entries/:id", handlers.DeleteTimeEntry)
 
 			// Tasks
 			protected.GET("/tasks", handlers.GetTasks)
+			protected.GET("/tasks/due", handlers.GetDueTasks)
+			protected.GET("/tasks/upcoming", handlers.GetUpcomingOccurrences)
 			protected.GET("/tasks/:id", handlers.GetTask)
 			protected.POST("/tasks", handlers.CreateTask)
 			protected.PUT("/tasks/:id", handlers.UpdateTask)
 			protected.DELETE("/tasks/:id", handlers.DeleteTask)
+			protected.POST("/tasks/:id/move", handlers.MoveTask)
+			protected.PUT("/tasks/:id/recurrence", handlers.SetTaskRecurrence)
+			protected.GET("/tasks/:id/checklist", handlers.GetChecklist)
+			protected.POST("/tasks/:id/checklist", handlers.CreateChecklistItem)
+			protected.PUT("/tasks/:id/checklist/:itemId", handlers.UpdateChecklistItem)
+			protected.DELETE("/tasks/:id/checklist/:itemId", handlers.DeleteChecklistItem)
+			protected.GET("/tasks/:id/dependencies", handlers.GetTaskDependencies)
+			protected.POST("/tasks/:id/dependencies", handlers.AddTaskDependency)
+			protected.DELETE("/tasks/:id/dependencies/:blockedById", handlers.RemoveTaskDependency)
+			protected.GET("/tasks/:id/comments", handlers.GetTaskComments)
+			protected.POST("/tasks/:id/comments", handlers.CreateTaskComment)
+			protected.PUT("/tasks/:id/comments/:commentId", handlers.UpdateTaskComment)
+			protected.DELETE("/tasks/:id/comments/:commentId", handlers.DeleteTaskComment)
+			protected.GET("/tasks/:id/activity", handlers.GetTaskActivity)
+			protected.GET("/tasks/:id/attachments", handlers.GetTaskAttachments)
+			protected.POST("/tasks/:id/attachments", handlers.UploadTaskAttachment)
+			protected.PUT("/tasks/:id/issue", handlers.LinkTaskIssue)
+			protected.POST("/tasks/:id/issue/sync", handlers.SyncTaskIssue)
+			protected.POST("/tasks/:id/issue/worklogs", handlers.PushTaskWorklogs)
+
+			// Attachments
+			protected.GET("/attachments/:id/download", handlers.DownloadAttachment)
+			protected.DELETE("/attachments/:id", handlers.DeleteAttachment)
+
+			// Milestones
+			protected.GET("/milestones", handlers.GetMilestones)
+			protected.GET("/milestones/:id", handlers.GetMilestone)
+			protected.POST("/milestones", handlers.CreateMilestone)
+			protected.PUT("/milestones/:id", handlers.UpdateMilestone)
+			protected.DELETE("/milestones/:id", handlers.DeleteMilestone)
+			protected.POST("/milestones/:id/complete", handlers.CompleteMilestone)
+			protected.POST("/milestones/:id/invoice", handlers.GenerateMilestoneInvoice)
 
 			// Analytics
 			protected.GET("/analytics/daily", handlers.GetDailyAnalytics)
 			protected.GET("/analytics/weekly", handlers.GetWeeklyAnalytics)
 			protected.GET("/analytics/monthly", handlers.GetMonthlyAnalytics)
+			protected.GET("/analytics/projects/rollup", handlers.GetProjectRollup)
+			protected.GET("/analytics/tags", handlers.GetTagAnalytics)
+			protected.GET("/analytics/earnings", handlers.GetEarningsAnalytics)
+
+			// Reports
+			protected.GET("/reports/profitability", handlers.GetProfitabilityReport)
+			protected.GET("/reports/estimates", handlers.GetEstimateReport)
+
+			// Search
+			protected.GET("/search", handlers.Search)
+
+			// Exchange rates
+			protected.GET("/exchange-rates", handlers.GetExchangeRates)
+			protected.POST("/exchange-rates", handlers.CreateExchangeRate)
+			protected.POST("/exchange-rates/import", handlers.ImportExchangeRates)
+			protected.DELETE("/exchange-rates/:id", handlers.DeleteExchangeRate)
 
 			// Invoices
 			protected.POST("/invoices/generate", handlers.GenerateInvoice)
+			protected.GET("/invoices", handlers.GetInvoices)
+			protected.POST("/invoices", handlers.CreateInvoice)
+			protected.GET("/invoices/:id", handlers.GetInvoice)
+			protected.PUT("/invoices/:id", handlers.UpdateInvoice)
+			protected.DELETE("/invoices/:id", handlers.DeleteInvoice)
+			protected.POST("/invoices/:id/issue", handlers.IssueInvoice)
+			protected.POST("/invoices/:id/send", handlers.SendInvoice)
+			protected.POST("/invoices/:id/void", handlers.VoidInvoice)
+			protected.POST("/invoices/:id/payments", handlers.RecordInvoicePayment)
+			protected.GET("/invoice-sequences", handlers.GetInvoiceSequences)
+			protected.PUT("/invoice-sequences/:scope", handlers.SaveInvoiceSequence)
 		}
 	}
 
@@ -4,6 +4,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +15,26 @@ type Config struct {
 	DatabaseURL string
 	JWTSecret   string
 	Port        string
+
+	// SchedulerInterval is how often background jobs such as spawning due
+	// recurring tasks run.
+	SchedulerInterval time.Duration
+
+	// ExchangePivotCurrency is tried first as the intermediate currency when
+	// two currencies have no rate between them
+	ExchangePivotCurrency string
+
+	// Attachment storage: STORAGE_DRIVER is "local" (files under
+	// StoragePath) or "s3"
+	StorageDriver string
+	StoragePath   string
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3PathStyle   bool
+	MaxUploadSize int64 // in bytes
 }
 
 var AppConfig Config
@@ -25,7 +48,32 @@ func LoadConfig() {
 		DatabaseURL: getEnv("DATABASE_URL", "host=localhost user=postgres password=postgres dbname=timetracker port=5432 sslmode=disable"),
 		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
 		Port:        getEnv("PORT", "8080"),
+
+		ExchangePivotCurrency: strings.ToUpper(getEnv("EXCHANGE_PIVOT_CURRENCY", "EUR")),
+
+		StorageDriver: getEnv("STORAGE_DRIVER", "local"),
+		StoragePath:   getEnv("STORAGE_PATH", "uploads"),
+		S3Endpoint:    getEnv("S3_ENDPOINT", ""),
+		S3Region:      getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:      getEnv("S3_BUCKET", ""),
+		S3AccessKey:   getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:   getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:   getEnv("S3_PATH_STYLE", "true") == "true",
+	}
+
+	maxUpload, err := strconv.ParseInt(getEnv("MAX_UPLOAD_SIZE", "26214400"), 10, 64)
+	if err != nil || maxUpload <= 0 {
+		log.Printf("Invalid MAX_UPLOAD_SIZE, using 25 MiB")
+		maxUpload = 25 << 20
+	}
+	AppConfig.MaxUploadSize = maxUpload
+
+	interval, err := time.ParseDuration(getEnv("SCHEDULER_INTERVAL", "5m"))
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid SCHEDULER_INTERVAL, using 5m")
+		interval = 5 * time.Minute
 	}
+	AppConfig.SchedulerInterval = interval
 }
 
 func getEnv(key, fallback string) string {
@@ -0,0 +1,262 @@
+package database
+
+import (
+	"log"
+	"timetracker/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SearchConfig is the Postgres text search configuration of the
+// search_vector columns. Queries must use the same one.
+const SearchConfig = "english"
+
+// backfill brings rows created before a feature existed in line with it. Each
+// statement is idempotent, so it is safe to run on every start.
+func backfill() {
+	taskStatuses := []string{models.TaskStatusTodo, models.TaskStatusInProgress, models.TaskStatusOnHold, models.TaskStatusCompleted}
+
+	statements := []struct {
+		name string
+		sql  string
+		args []interface{}
+	}{
+		{
+			// Projects created before memberships existed are owned by their creator
+			name: "project owners",
+			sql: `INSERT INTO project_members (created_at, updated_at, project_id, user_id, role)
+				SELECT NOW(), NOW(), p.id, p.user_id, ? FROM projects p
+				WHERE p.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM project_members m WHERE m.project_id = p.id)`,
+			args: []interface{}{models.ProjectRoleOwner},
+		},
+		{
+			// Every user gets a personal workspace
+			name: "personal workspaces",
+			sql: `INSERT INTO workspaces (created_at, updated_at, name, personal, owner_id)
+				SELECT NOW(), NOW(), 'Personal', TRUE, u.id FROM users u
+				WHERE NOT EXISTS (SELECT 1 FROM workspaces w WHERE w.owner_id = u.id AND w.personal)`,
+		},
+		{
+			name: "personal workspace owners",
+			sql: `INSERT INTO workspace_members (created_at, updated_at, workspace_id, user_id, role)
+				SELECT NOW(), NOW(), w.id, w.owner_id, ? FROM workspaces w
+				WHERE NOT EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = w.id AND m.user_id = w.owner_id)`,
+			args: []interface{}{models.WorkspaceRoleOwner},
+		},
+		{
+			name: "active workspaces",
+			sql: `UPDATE users u SET active_workspace_id = w.id FROM workspaces w
+				WHERE w.owner_id = u.id AND w.personal AND u.active_workspace_id IS NULL`,
+		},
+		{
+			// Existing data moves into its creator's personal workspace
+			name: "project workspaces",
+			sql: `UPDATE projects p SET workspace_id = w.id FROM workspaces w
+				WHERE w.owner_id = p.user_id AND w.personal AND COALESCE(p.workspace_id, 0) = 0`,
+		},
+		{
+			name: "task workspaces",
+			sql: `UPDATE tasks t SET workspace_id = p.workspace_id FROM projects p
+				WHERE p.id = t.project_id AND COALESCE(t.workspace_id, 0) = 0`,
+		},
+		{
+			name: "time entry workspaces",
+			sql: `UPDATE time_entries e SET workspace_id = p.workspace_id FROM projects p
+				WHERE p.id = e.project_id AND COALESCE(e.workspace_id, 0) = 0`,
+		},
+		{
+			name: "template workspaces",
+			sql: `UPDATE project_templates t SET workspace_id = w.id FROM workspaces w
+				WHERE w.owner_id = t.user_id AND w.personal AND COALESCE(t.workspace_id, 0) = 0`,
+		},
+		{
+			// Exchange rates used to belong to a user rather than a workspace
+			name: "exchange rate workspaces",
+			sql: `UPDATE exchange_rates r SET workspace_id = w.id FROM workspaces w
+				WHERE w.owner_id = r.user_id AND w.personal AND COALESCE(r.workspace_id, 0) = 0`,
+		},
+		{
+			name: "per-user exchange rate index",
+			sql:  `DROP INDEX IF EXISTS idx_exchange_rate`,
+		},
+		{
+			name: "exchange rate index",
+			sql: `CREATE UNIQUE INDEX IF NOT EXISTS idx_exchange_rate_workspace
+				ON exchange_rates (workspace_id, date, base_currency, quote_currency)`,
+		},
+		{
+			// Issued invoice numbers are unique within a workspace, whichever
+			// sequence they came from
+			name: "invoice number index",
+			sql: `CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_workspace_number
+				ON invoices (workspace_id, number) WHERE number <> ''`,
+		},
+		{
+			// Task statuses used to be free-form; fold spellings like
+			// "in progress" onto the defined statuses and reset the rest
+			name: "task status spelling",
+			sql: `UPDATE tasks SET status = UPPER(REPLACE(TRIM(status), ' ', '_'))
+				WHERE status <> UPPER(REPLACE(TRIM(status), ' ', '_'))
+				AND UPPER(REPLACE(TRIM(status), ' ', '_')) IN (?)`,
+			args: []interface{}{taskStatuses},
+		},
+		{
+			name: "unknown task statuses",
+			sql:  `UPDATE tasks SET status = ? WHERE status IS NULL OR status NOT IN (?)`,
+			args: []interface{}{models.TaskStatusTodo, taskStatuses},
+		},
+		{
+			name: "task completion times",
+			sql: `UPDATE tasks SET completed_at = updated_at, started_at = COALESCE(started_at, created_at)
+				WHERE status = ? AND completed_at IS NULL`,
+			args: []interface{}{models.TaskStatusCompleted},
+		},
+		{
+			// Number each board column in its current order
+			name: "task positions",
+			sql: `UPDATE tasks SET position = r.position FROM (
+					SELECT id, ROW_NUMBER() OVER (PARTITION BY project_id, status ORDER BY position, created_at, id) - 1 AS position
+					FROM tasks WHERE deleted_at IS NULL
+				) r
+				WHERE r.id = tasks.id AND tasks.position IS DISTINCT FROM r.position`,
+		},
+		{
+			// Full-text search vectors are generated columns Postgres keeps
+			// up to date; task tags are matched separately at query time
+			name: "project search vectors",
+			sql: `ALTER TABLE projects ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('` + SearchConfig + `', COALESCE(name, '')), 'A') ||
+					setweight(to_tsvector('` + SearchConfig + `', COALESCE(description, '')), 'B')) STORED`,
+		},
+		{
+			name: "task search vectors",
+			sql: `ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('` + SearchConfig + `', COALESCE(title, '')), 'A') ||
+					setweight(to_tsvector('` + SearchConfig + `', COALESCE(description, '')), 'B')) STORED`,
+		},
+		{
+			name: "time entry search vectors",
+			sql: `ALTER TABLE time_entries ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+					to_tsvector('` + SearchConfig + `', COALESCE(notes, ''))) STORED`,
+		},
+		{
+			name: "project search index",
+			sql:  `CREATE INDEX IF NOT EXISTS idx_projects_search ON projects USING GIN (search_vector)`,
+		},
+		{
+			name: "task search index",
+			sql:  `CREATE INDEX IF NOT EXISTS idx_tasks_search ON tasks USING GIN (search_vector)`,
+		},
+		{
+			name: "time entry search index",
+			sql:  `CREATE INDEX IF NOT EXISTS idx_time_entries_search ON time_entries USING GIN (search_vector)`,
+		},
+	}
+
+	for _, stmt := range statements {
+		if err := DB.Exec(stmt.sql, stmt.args...).Error; err != nil {
+			log.Printf("Backfill of %s failed: %v", stmt.name, err)
+		}
+	}
+
+	migrateLegacyTags()
+	uniqueTagNames()
+}
+
+// migrateLegacyTags moves the tags that tasks and template tasks used to keep
+// in a text[] column onto Tag rows, matching names regardless of case, and
+// drops the old column once its rows are linked.
+func migrateLegacyTags() {
+	sources := []struct {
+		table     string
+		joinTable string
+		column    string
+		// rows selects id, name, workspace_id and user_id per legacy tag
+		rows string
+	}{
+		{
+			table:     "tasks",
+			joinTable: "task_tags",
+			column:    "task_id",
+			rows: `SELECT t.id, TRIM(tag) AS name, t.workspace_id, t.user_id
+				FROM tasks t CROSS JOIN LATERAL unnest(t.tags) AS tag`,
+		},
+		{
+			table:     "project_template_tasks",
+			joinTable: "project_template_task_tags",
+			column:    "project_template_task_id",
+			rows: `SELECT t.id, TRIM(tag) AS name, p.workspace_id, p.user_id
+				FROM project_template_tasks t JOIN project_templates p ON p.id = t.template_id
+				CROSS JOIN LATERAL unnest(t.tags) AS tag`,
+		},
+	}
+
+	for _, source := range sources {
+		if !DB.Migrator().HasColumn(source.table, "tags") {
+			continue
+		}
+
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec(`INSERT INTO tags (created_at, updated_at, name, color, workspace_id, user_id)
+				SELECT DISTINCT ON (r.workspace_id, LOWER(r.name)) NOW(), NOW(), r.name, ?, r.workspace_id, r.user_id
+				FROM (`+source.rows+`) r
+				WHERE r.name <> '' AND NOT EXISTS (
+					SELECT 1 FROM tags g WHERE g.workspace_id = r.workspace_id AND LOWER(g.name) = LOWER(r.name) AND g.deleted_at IS NULL
+				)
+				ORDER BY r.workspace_id, LOWER(r.name), r.id`, models.DefaultTagColor).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Exec(`INSERT INTO ` + source.joinTable + ` (` + source.column + `, tag_id)
+				SELECT DISTINCT r.id, g.id FROM (` + source.rows + `) r
+				JOIN tags g ON g.workspace_id = r.workspace_id AND LOWER(g.name) = LOWER(r.name) AND g.deleted_at IS NULL
+				ON CONFLICT DO NOTHING`).Error
+			if err != nil {
+				return err
+			}
+
+			return tx.Exec(`ALTER TABLE ` + source.table + ` DROP COLUMN tags`).Error
+		})
+		if err != nil {
+			log.Printf("Migration of %s tags failed: %v", source.table, err)
+		}
+	}
+}
+
+// uniqueTagNames merges tags whose names differ only in case into the oldest
+// of them, then indexes the lowercased names so no new duplicates appear.
+func uniqueTagNames() {
+	joinTables := []struct{ table, column string }{
+		{"task_tags", "task_id"},
+		{"time_entry_tags", "time_entry_id"},
+		{"project_template_task_tags", "project_template_task_id"},
+	}
+	duplicates := `SELECT id, keep FROM (
+			SELECT id, MIN(id) OVER (PARTITION BY workspace_id, LOWER(name)) AS keep
+			FROM tags WHERE deleted_at IS NULL
+		) d WHERE id <> keep`
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, join := range joinTables {
+			err := tx.Exec(`INSERT INTO ` + join.table + ` (` + join.column + `, tag_id)
+				SELECT j.` + join.column + `, d.keep FROM ` + join.table + ` j JOIN (` + duplicates + `) d ON d.id = j.tag_id
+				ON CONFLICT DO NOTHING`).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Exec(`DELETE FROM ` + join.table + ` WHERE tag_id IN (SELECT id FROM (` + duplicates + `) d)`).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec(`DELETE FROM tags WHERE id IN (SELECT id FROM (` + duplicates + `) d)`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_name_lower ON tags (workspace_id, LOWER(name)) WHERE deleted_at IS NULL`).Error
+	})
+	if err != nil {
+		log.Printf("Backfill of unique tag names failed: %v", err)
+	}
+}
@@ -20,7 +20,35 @@ func InitDB() *gorm.DB {
 	}
 
 	// Auto migrate the schema
-	DB.AutoMigrate(&models.User{}, &models.Project{}, &models.TimeEntry{}, &models.Task{})
+	DB.AutoMigrate(
+		&models.User{},
+		&models.Project{},
+		&models.Tag{},
+		&models.TimeEntry{},
+		&models.Task{},
+		&models.TaskChecklistItem{},
+		&models.TaskDependency{},
+		&models.TaskComment{},
+		&models.TaskStatusChange{},
+		&models.Milestone{},
+		&models.ProjectTemplate{},
+		&models.ProjectTemplateTask{},
+		&models.ExchangeRate{},
+		&models.ProjectMember{},
+		&models.ProjectInvitation{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.Client{},
+		&models.CustomField{},
+		&models.Attachment{},
+		&models.IssueTracker{},
+		&models.Invoice{},
+		&models.InvoiceLineItem{},
+		&models.InvoicePayment{},
+		&models.InvoiceSequence{},
+	)
+
+	backfill()
 
 	return DB
 }
@@ -0,0 +1,229 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate is the number of Quote units one Base unit buys on Date.
+type Rate struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Rate  float64
+}
+
+var ErrNoRate = errors.New("no exchange rate available")
+
+// NormalizeCurrency upper-cases a currency code and checks it looks like an
+// ISO 4217 code.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency code %q", code)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency code %q", code)
+		}
+	}
+	return code, nil
+}
+
+// ParseCSV reads rows of date,base,quote,rate. A header row is skipped when
+// present.
+func ParseCSV(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	var rates []Rate
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+
+		if len(record) != 4 {
+			return nil, fmt.Errorf("line %d: expected 4 columns, got %d", line, len(record))
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+
+		rate, err := parseRate(record[0], record[1], record[2], record[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECBXML reads the European Central Bank reference rate format, in which
+// every rate is quoted against EUR.
+func ParseECBXML(r io.Reader) ([]Rate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	var rates []Rate
+	for _, day := range envelope.Days {
+		for _, cube := range day.Rates {
+			rate, err := parseRate(day.Time, "EUR", cube.Currency, cube.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", day.Time, cube.Currency, err)
+			}
+			rates = append(rates, rate)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.New("no rates found in XML")
+	}
+
+	return rates, nil
+}
+
+func parseRate(date, base, quote, value string) (Rate, error) {
+	d, err := time.Parse("2006-01-02", strings.TrimSpace(date))
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid date %q", date)
+	}
+	b, err := NormalizeCurrency(base)
+	if err != nil {
+		return Rate{}, err
+	}
+	q, err := NormalizeCurrency(quote)
+	if err != nil {
+		return Rate{}, err
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || v <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q", value)
+	}
+	return Rate{Date: d, Base: b, Quote: q, Rate: v}, nil
+}
+
+type pair struct {
+	from, to string
+}
+
+type point struct {
+	date time.Time
+	rate float64
+}
+
+// Converter converts amounts using the most recent rate on or before the date
+// of the amount. Inverse rates are used when only the opposite pair is known,
+// and a single intermediate currency is tried when neither is: the pivot
+// first, then the other currencies in alphabetical order, so a conversion
+// always goes the same way.
+type Converter struct {
+	series map[pair][]point
+	via    []string // intermediate currencies in the order they are tried
+}
+
+// NewConverter builds a converter from rates. pivot, such as EUR for ECB
+// rates, may be empty.
+func NewConverter(rates []Rate, pivot string) *Converter {
+	conv := &Converter{series: make(map[pair][]point)}
+
+	currencies := make(map[string]bool)
+	for _, r := range rates {
+		key := pair{r.Base, r.Quote}
+		conv.series[key] = append(conv.series[key], point{r.Date, r.Rate})
+		currencies[r.Base] = true
+		currencies[r.Quote] = true
+	}
+	for key := range conv.series {
+		points := conv.series[key]
+		sort.Slice(points, func(i, j int) bool { return points[i].date.Before(points[j].date) })
+	}
+
+	for code := range currencies {
+		if code != pivot {
+			conv.via = append(conv.via, code)
+		}
+	}
+	sort.Strings(conv.via)
+	if currencies[pivot] {
+		conv.via = append([]string{pivot}, conv.via...)
+	}
+	return conv
+}
+
+// Rate returns how many units of to one unit of from buys on date.
+func (conv *Converter) Rate(from, to string, date time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := conv.direct(from, to, date); ok {
+		return rate, nil
+	}
+	for _, via := range conv.via {
+		if via == from || via == to {
+			continue
+		}
+		first, ok := conv.direct(from, via, date)
+		if !ok {
+			continue
+		}
+		second, ok := conv.direct(via, to, date)
+		if !ok {
+			continue
+		}
+		return first * second, nil
+	}
+	return 0, fmt.Errorf("%w for %s/%s on %s", ErrNoRate, from, to, date.Format("2006-01-02"))
+}
+
+func (conv *Converter) Convert(amount float64, from, to string, date time.Time) (float64, error) {
+	rate, err := conv.Rate(from, to, date)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+func (conv *Converter) direct(from, to string, date time.Time) (float64, bool) {
+	if rate, ok := conv.lookup(pair{from, to}, date); ok {
+		return rate, true
+	}
+	if rate, ok := conv.lookup(pair{to, from}, date); ok {
+		return 1 / rate, true
+	}
+	return 0, false
+}
+
+func (conv *Converter) lookup(key pair, date time.Time) (float64, bool) {
+	points := conv.series[key]
+	i := sort.Search(len(points), func(i int) bool { return points[i].date.After(date) })
+	if i == 0 {
+		return 0, false
+	}
+	return points[i-1].rate, true
+}
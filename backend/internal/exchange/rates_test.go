@@ -0,0 +1,78 @@
+package exchange
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConverterRateCrossCurrency(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	// USD/JPY is only known through EUR or CHF, and the two routes disagree
+	rates := []Rate{
+		{Date: day, Base: "EUR", Quote: "USD", Rate: 1.10},
+		{Date: day, Base: "EUR", Quote: "JPY", Rate: 165},
+		{Date: day, Base: "CHF", Quote: "USD", Rate: 1.12},
+		{Date: day, Base: "CHF", Quote: "JPY", Rate: 170},
+	}
+
+	tests := []struct {
+		name  string
+		pivot string
+		want  float64
+	}{
+		{"pivot", "EUR", 165 / 1.10},
+		{"other pivot", "CHF", 170 / 1.12},
+		{"alphabetical without pivot", "", 170 / 1.12},
+		{"alphabetical with unknown pivot", "GBP", 170 / 1.12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got, err := NewConverter(rates, tt.pivot).Rate("USD", "JPY", day)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if math.Abs(got-tt.want) > 1e-9 {
+					t.Fatalf("Rate(USD, JPY) = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestConverterRate(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	conv := NewConverter([]Rate{
+		{Date: feb, Base: "EUR", Quote: "USD", Rate: 1.2},
+		{Date: jan, Base: "EUR", Quote: "USD", Rate: 1.1},
+	}, "EUR")
+
+	tests := []struct {
+		name     string
+		from, to string
+		date     time.Time
+		want     float64
+		err      error
+	}{
+		{"same currency", "USD", "USD", jan, 1, nil},
+		{"direct", "EUR", "USD", jan.AddDate(0, 0, 10), 1.1, nil},
+		{"latest before date", "EUR", "USD", feb.AddDate(0, 0, 3), 1.2, nil},
+		{"inverse", "USD", "EUR", feb, 1 / 1.2, nil},
+		{"before first rate", "EUR", "USD", jan.AddDate(0, 0, -1), 0, ErrNoRate},
+		{"unknown currency", "EUR", "GBP", feb, 0, ErrNoRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := conv.Rate(tt.from, tt.to, tt.date)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Rate() error = %v, want %v", err, tt.err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Rate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
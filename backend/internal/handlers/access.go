@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var projectRoleRank = map[string]int{
+	models.ProjectRoleViewer:  1,
+	models.ProjectRoleMember:  2,
+	models.ProjectRoleManager: 3,
+	models.ProjectRoleOwner:   4,
+}
+
+var workspaceRoleRank = map[string]int{
+	models.WorkspaceRoleMember: 1,
+	models.WorkspaceRoleAdmin:  2,
+	models.WorkspaceRoleOwner:  3,
+}
+
+// isWorkspaceAdmin reports whether the caller administers the active
+// workspace. Workspace admins manage every project in it.
+func isWorkspaceAdmin(c *gin.Context) bool {
+	return workspaceRoleRank[utils.GetWorkspaceRole(c)] >= workspaceRoleRank[models.WorkspaceRoleAdmin]
+}
+
+// projectRole returns the caller's role on a project in the active workspace,
+// or "" when the caller has no access to it.
+func projectRole(c *gin.Context, projectID interface{}) string {
+	var project models.Project
+	err := database.DB.Select("id").
+		Where("id = ? AND workspace_id = ?", projectID, utils.GetWorkspaceID(c)).
+		First(&project).Error
+	if err != nil {
+		return ""
+	}
+
+	role := ""
+	var member models.ProjectMember
+	if database.DB.Where("project_id = ? AND user_id = ?", project.ID, utils.GetUserID(c)).First(&member).Error == nil {
+		role = member.Role
+	}
+	if isWorkspaceAdmin(c) && projectRoleRank[role] < projectRoleRank[models.ProjectRoleManager] {
+		role = models.ProjectRoleManager
+	}
+	return role
+}
+
+// hasProjectRole reports whether the caller holds at least minRole on the
+// project.
+func hasProjectRole(c *gin.Context, projectID interface{}, minRole string) bool {
+	return projectRoleRank[projectRole(c, projectID)] >= projectRoleRank[minRole]
+}
+
+// memberProjectIDs is a subquery selecting the projects of the active
+// workspace on which the caller holds at least minRole.
+func memberProjectIDs(c *gin.Context, minRole string) *gorm.DB {
+	query := database.DB.Model(&models.Project{}).
+		Select("id").
+		Where("workspace_id = ?", utils.GetWorkspaceID(c))
+	if isWorkspaceAdmin(c) && projectRoleRank[minRole] <= projectRoleRank[models.ProjectRoleManager] {
+		return query
+	}
+
+	var roles []string
+	for role, rank := range projectRoleRank {
+		if rank >= projectRoleRank[minRole] {
+			roles = append(roles, role)
+		}
+	}
+	return query.Where("id IN (?)", database.DB.Model(&models.ProjectMember{}).
+		Select("project_id").
+		Where("user_id = ? AND role IN ?", utils.GetUserID(c), roles))
+}
+
+// visibleTimeEntries scopes time entries to those the caller may see: their
+// own entries on projects they belong to, and everyone's entries on projects
+// they manage.
+func visibleTimeEntries(c *gin.Context) *gorm.DB {
+	return database.DB.Where(
+		"((user_id = ? AND project_id IN (?)) OR project_id IN (?))",
+		utils.GetUserID(c), memberProjectIDs(c, models.ProjectRoleViewer), memberProjectIDs(c, models.ProjectRoleManager),
+	)
+}
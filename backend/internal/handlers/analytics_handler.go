@@ -2,9 +2,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"sort"
+	"strconv"
 	"time"
 	"timetracker/internal/database"
+	"timetracker/internal/exchange"
 	"timetracker/internal/models"
 	"timetracker/internal/utils"
 
@@ -26,7 +30,7 @@ func GetDailyAnalytics(c *gin.Context) {
 
 	dayAgo := time.Now().AddDate(0, 0, -1)
 
-	err := database.DB.Where("user_id = ? AND start_time >= ?", userID, dayAgo).
+	err := database.DB.Where("user_id = ? AND workspace_id = ? AND start_time >= ?", userID, utils.GetWorkspaceID(c), dayAgo).
 		Find(&entries).Error
 
 	if err != nil {
@@ -46,9 +50,9 @@ func GetDailyAnalytics(c *gin.Context) {
 	var completedTasks int64
 	var totalProjects int64
 
-	database.DB.Model(&models.Task{}).Where("user_id = ?", userID).Count(&totalTasks)
-	database.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "COMPLETED").Count(&completedTasks)
-	database.DB.Model(&models.Project{}).Where("user_id = ?", userID).Count(&totalProjects)
+	database.DB.Model(&models.Task{}).Where("project_id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Count(&totalTasks)
+	database.DB.Model(&models.Task{}).Where("project_id IN (?) AND status = ?", memberProjectIDs(c, models.ProjectRoleViewer), models.TaskStatusCompleted).Count(&completedTasks)
+	database.DB.Model(&models.Project{}).Where("id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Count(&totalProjects)
 
 	// Convert to response format
 	for date, hours := range dailyTotals {
@@ -71,7 +75,7 @@ func GetWeeklyAnalytics(c *gin.Context) {
 
 	weekAgo := time.Now().AddDate(0, 0, -7)
 
-	err := database.DB.Where("user_id = ? AND start_time >= ?", userID, weekAgo).
+	err := database.DB.Where("user_id = ? AND workspace_id = ? AND start_time >= ?", userID, utils.GetWorkspaceID(c), weekAgo).
 		Find(&entries).Error
 
 	if err != nil {
@@ -91,9 +95,9 @@ func GetWeeklyAnalytics(c *gin.Context) {
 	var completedTasks int64
 	var totalProjects int64
 
-	database.DB.Model(&models.Task{}).Where("user_id = ?", userID).Count(&totalTasks)
-	database.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "COMPLETED").Count(&completedTasks)
-	database.DB.Model(&models.Project{}).Where("user_id = ?", userID).Count(&totalProjects)
+	database.DB.Model(&models.Task{}).Where("project_id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Count(&totalTasks)
+	database.DB.Model(&models.Task{}).Where("project_id IN (?) AND status = ?", memberProjectIDs(c, models.ProjectRoleViewer), models.TaskStatusCompleted).Count(&completedTasks)
+	database.DB.Model(&models.Project{}).Where("id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Count(&totalProjects)
 
 	// Convert to response format
 	for date, hours := range weeklyTotals {
@@ -116,7 +120,7 @@ func GetMonthlyAnalytics(c *gin.Context) {
 
 	monthAgo := time.Now().AddDate(0, -1, 0)
 
-	err := database.DB.Where("user_id = ? AND start_time >= ?", userID, monthAgo).
+	err := database.DB.Where("user_id = ? AND workspace_id = ? AND start_time >= ?", userID, utils.GetWorkspaceID(c), monthAgo).
 		Find(&entries).Error
 
 	if err != nil {
@@ -136,9 +140,9 @@ func GetMonthlyAnalytics(c *gin.Context) {
 	var completedTasks int64
 	var totalProjects int64
 
-	database.DB.Model(&models.Task{}).Where("user_id = ?", userID).Count(&totalTasks)
-	database.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "COMPLETED").Count(&completedTasks)
-	database.DB.Model(&models.Project{}).Where("user_id = ?", userID).Count(&totalProjects)
+	database.DB.Model(&models.Task{}).Where("project_id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Count(&totalTasks)
+	database.DB.Model(&models.Task{}).Where("project_id IN (?) AND status = ?", memberProjectIDs(c, models.ProjectRoleViewer), models.TaskStatusCompleted).Count(&completedTasks)
+	database.DB.Model(&models.Project{}).Where("id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Count(&totalProjects)
 
 	// Convert to response format
 	for date, hours := range monthlyTotals {
@@ -153,3 +157,432 @@ func GetMonthlyAnalytics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, result)
 }
+
+type EarningsDay struct {
+	Date     string  `json:"date"`
+	Hours    float64 `json:"hours"`
+	Earnings float64 `json:"earnings"`
+}
+
+type EarningsProject struct {
+	ProjectID   uint    `json:"projectId"`
+	ProjectName string  `json:"projectName"`
+	Currency    string  `json:"currency"`
+	Hours       float64 `json:"hours"`
+	Earnings    float64 `json:"earnings"`  // in the project currency
+	Converted   float64 `json:"converted"` // in the report currency
+}
+
+type EarningsResponse struct {
+	Currency      string            `json:"currency"`
+	StartDate     string            `json:"startDate"`
+	EndDate       string            `json:"endDate"`
+	TotalHours    float64           `json:"totalHours"`
+	TotalEarnings float64           `json:"totalEarnings"`
+	Days          []EarningsDay     `json:"days"`
+	Projects      []EarningsProject `json:"projects"`
+	MissingRates  []string          `json:"missingRates,omitempty"`
+}
+
+// GetEarningsAnalytics reports earnings converted into the user's base
+// currency (or ?currency=) using the exchange rate on the date of the work.
+// The range defaults to the last 30 days.
+func GetEarningsAnalytics(c *gin.Context) {
+	userID := utils.GetUserID(c)
+
+	startDate, endDate, err := parseDateRange(c, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	currency := user.BaseCurrency
+	if c.Query("currency") != "" {
+		currency, err = exchange.NormalizeCurrency(c.Query("currency"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var projects []models.Project
+	if err := database.DB.Where("id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching projects"})
+		return
+	}
+	projectsByID := make(map[uint]models.Project)
+	for _, p := range projects {
+		projectsByID[p.ID] = p
+	}
+
+	var entries []models.TimeEntry
+	err = database.DB.Where("user_id = ? AND start_time >= ? AND start_time < ?", userID, startDate, endDate.AddDate(0, 0, 1)).
+		Order("start_time").
+		Find(&entries).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching analytics"})
+		return
+	}
+
+	converter, err := loadConverter(utils.GetWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching exchange rates"})
+		return
+	}
+
+	response := EarningsResponse{
+		Currency:  currency,
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+	}
+	days := make(map[string]*EarningsDay)
+	byProject := make(map[uint]*EarningsProject)
+	missing := make(map[string]bool)
+
+	for _, entry := range entries {
+		project, ok := projectsByID[entry.ProjectID]
+		if !ok {
+			continue
+		}
+
+		hours := float64(entry.Duration) / 3600
+		earnings := hours * project.HourlyRate
+		converted, err := converter.Convert(earnings, project.Currency, currency, entry.StartTime)
+		if err != nil {
+			missing[project.Currency+"/"+currency+" "+entry.StartTime.Format("2006-01-02")] = true
+			converted = 0
+		}
+
+		date := entry.StartTime.Format("2006-01-02")
+		if days[date] == nil {
+			days[date] = &EarningsDay{Date: date}
+		}
+		days[date].Hours += hours
+		days[date].Earnings += converted
+
+		if byProject[project.ID] == nil {
+			byProject[project.ID] = &EarningsProject{
+				ProjectID:   project.ID,
+				ProjectName: project.Name,
+				Currency:    project.Currency,
+			}
+		}
+		byProject[project.ID].Hours += hours
+		byProject[project.ID].Earnings += earnings
+		byProject[project.ID].Converted += converted
+
+		response.TotalHours += hours
+		response.TotalEarnings += converted
+	}
+
+	for _, day := range days {
+		response.Days = append(response.Days, *day)
+	}
+	sort.Slice(response.Days, func(i, j int) bool { return response.Days[i].Date < response.Days[j].Date })
+
+	for _, p := range byProject {
+		response.Projects = append(response.Projects, *p)
+	}
+	sort.Slice(response.Projects, func(i, j int) bool { return response.Projects[i].ProjectID < response.Projects[j].ProjectID })
+
+	for key := range missing {
+		response.MissingRates = append(response.MissingRates, key)
+	}
+	sort.Strings(response.MissingRates)
+
+	c.JSON(http.StatusOK, response)
+}
+
+type ProjectRollup struct {
+	ProjectID    uint    `json:"projectId"`
+	ProjectName  string  `json:"projectName"`
+	ParentID     *uint   `json:"parentId,omitempty"`
+	Hours        float64 `json:"hours"`
+	Earnings     float64 `json:"earnings"`
+	BudgetHours  float64 `json:"budgetHours"`
+	BudgetAmount float64 `json:"budgetAmount"`
+
+	// Totals include every visible sub-project
+	TotalHours        float64         `json:"totalHours"`
+	TotalEarnings     float64         `json:"totalEarnings"`
+	TotalBudgetHours  float64         `json:"totalBudgetHours"`
+	TotalBudgetAmount float64         `json:"totalBudgetAmount"`
+	Children          []ProjectRollup `json:"children"`
+}
+
+type ProjectRollupResponse struct {
+	Currency     string          `json:"currency"`
+	StartDate    string          `json:"startDate"`
+	EndDate      string          `json:"endDate"`
+	Projects     []ProjectRollup `json:"projects"`
+	MissingRates []string        `json:"missingRates,omitempty"`
+}
+
+// GetProjectRollup returns the project tree with hours, earnings and budgets
+// summed up through the hierarchy. Amounts are converted into the user's base
+// currency (or ?currency=); budgets at the rate of the end date. ?project_id=
+// limits the response to one sub-tree. Without start_date all work up to the
+// end date is counted.
+func GetProjectRollup(c *gin.Context) {
+	userID := utils.GetUserID(c)
+
+	startDate, endDate, err := parseDateRange(c, time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	currency := user.BaseCurrency
+	if c.Query("currency") != "" {
+		currency, err = exchange.NormalizeCurrency(c.Query("currency"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var projects []models.Project
+	if err := database.DB.Where("id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Order("name").Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching projects"})
+		return
+	}
+
+	var entries []models.TimeEntry
+	err = visibleTimeEntries(c).
+		Where("start_time >= ? AND start_time < ?", startDate, endDate.AddDate(0, 0, 1)).
+		Find(&entries).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching analytics"})
+		return
+	}
+
+	converter, err := loadConverter(utils.GetWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching exchange rates"})
+		return
+	}
+
+	missing := make(map[string]bool)
+	convert := func(amount float64, from string, date time.Time) float64 {
+		if amount == 0 {
+			return 0
+		}
+		value, err := converter.Convert(amount, from, currency, date)
+		if err != nil {
+			missing[from+"/"+currency+" "+date.Format("2006-01-02")] = true
+			return 0
+		}
+		return value
+	}
+
+	nodes := make(map[uint]*ProjectRollup)
+	for _, p := range projects {
+		nodes[p.ID] = &ProjectRollup{
+			ProjectID:    p.ID,
+			ProjectName:  p.Name,
+			ParentID:     p.ParentID,
+			BudgetHours:  p.BudgetHours,
+			BudgetAmount: convert(p.BudgetAmount, p.Currency, endDate),
+		}
+	}
+
+	projectsByID := make(map[uint]models.Project)
+	for _, p := range projects {
+		projectsByID[p.ID] = p
+	}
+	for _, entry := range entries {
+		node, ok := nodes[entry.ProjectID]
+		if !ok {
+			continue
+		}
+		project := projectsByID[entry.ProjectID]
+		hours := float64(entry.Duration) / 3600
+		node.Hours += hours
+		node.Earnings += convert(hours*project.HourlyRate, project.Currency, entry.StartTime)
+	}
+
+	children := make(map[uint][]uint)
+	var roots []uint
+	for _, p := range projects {
+		if p.ParentID != nil && nodes[*p.ParentID] != nil {
+			children[*p.ParentID] = append(children[*p.ParentID], p.ID)
+		} else {
+			roots = append(roots, p.ID)
+		}
+	}
+
+	if v := c.Query("project_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || nodes[uint(id)] == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		roots = []uint{uint(id)}
+	}
+
+	response := ProjectRollupResponse{
+		Currency:  currency,
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Projects:  []ProjectRollup{},
+	}
+	for _, id := range roots {
+		response.Projects = append(response.Projects, buildProjectRollup(id, nodes, children))
+	}
+
+	for key := range missing {
+		response.MissingRates = append(response.MissingRates, key)
+	}
+	sort.Strings(response.MissingRates)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func buildProjectRollup(id uint, nodes map[uint]*ProjectRollup, children map[uint][]uint) ProjectRollup {
+	node := *nodes[id]
+	node.TotalHours = node.Hours
+	node.TotalEarnings = node.Earnings
+	node.TotalBudgetHours = node.BudgetHours
+	node.TotalBudgetAmount = node.BudgetAmount
+	node.Children = []ProjectRollup{}
+
+	for _, childID := range children[id] {
+		child := buildProjectRollup(childID, nodes, children)
+		node.TotalHours += child.TotalHours
+		node.TotalEarnings += child.TotalEarnings
+		node.TotalBudgetHours += child.TotalBudgetHours
+		node.TotalBudgetAmount += child.TotalBudgetAmount
+		node.Children = append(node.Children, child)
+	}
+
+	return node
+}
+
+type TagHours struct {
+	TagID   uint    `json:"tagId"`
+	Name    string  `json:"name"`
+	Color   string  `json:"color"`
+	Hours   float64 `json:"hours"`
+	Entries int     `json:"entries"`
+}
+
+type TagAnalyticsResponse struct {
+	StartDate  string     `json:"startDate"`
+	EndDate    string     `json:"endDate"`
+	TotalHours float64    `json:"totalHours"`
+	Tags       []TagHours `json:"tags"`
+	Untagged   float64    `json:"untaggedHours"`
+}
+
+// GetTagAnalytics breaks the caller's visible hours down by tag. An entry
+// counts towards its own tags and the tags of its task, so an entry with
+// several tags is counted under each of them and the tag rows can add up to
+// more than the total. The range defaults to the last 30 days.
+func GetTagAnalytics(c *gin.Context) {
+	startDate, endDate, err := parseDateRange(c, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var entries []models.TimeEntry
+	err = visibleTimeEntries(c).
+		Where("start_time >= ? AND start_time < ?", startDate, endDate.AddDate(0, 0, 1)).
+		Preload("Tags").
+		Find(&entries).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching analytics"})
+		return
+	}
+
+	taskIDs := make([]uint, 0)
+	for _, entry := range entries {
+		if entry.TaskID != 0 {
+			taskIDs = append(taskIDs, entry.TaskID)
+		}
+	}
+	var tasks []models.Task
+	if err := database.DB.Preload("Tags").Where("id IN ?", taskIDs).Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+	taskTags := make(map[uint][]models.Tag)
+	for _, task := range tasks {
+		taskTags[task.ID] = task.Tags
+	}
+
+	response := TagAnalyticsResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Tags:      []TagHours{},
+	}
+	byTag := make(map[uint]*TagHours)
+	for _, entry := range entries {
+		hours := float64(entry.Duration) / 3600
+		response.TotalHours += hours
+
+		seen := make(map[uint]bool)
+		for _, tag := range append(entry.Tags, taskTags[entry.TaskID]...) {
+			if seen[tag.ID] {
+				continue
+			}
+			seen[tag.ID] = true
+			if byTag[tag.ID] == nil {
+				byTag[tag.ID] = &TagHours{TagID: tag.ID, Name: tag.Name, Color: tag.Color}
+			}
+			byTag[tag.ID].Hours += hours
+			byTag[tag.ID].Entries++
+		}
+		if len(seen) == 0 {
+			response.Untagged += hours
+		}
+	}
+
+	for _, row := range byTag {
+		response.Tags = append(response.Tags, *row)
+	}
+	sort.Slice(response.Tags, func(i, j int) bool { return response.Tags[i].Hours > response.Tags[j].Hours })
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseDateRange reads the inclusive start_date and end_date query params
+// (YYYY-MM-DD). A missing start falls back to defaultStart and a missing end
+// to today.
+func parseDateRange(c *gin.Context, defaultStart time.Time) (time.Time, time.Time, error) {
+	startDate := time.Date(defaultStart.Year(), defaultStart.Month(), defaultStart.Day(), 0, 0, 0, 0, time.UTC)
+	now := time.Now()
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if v := c.Query("start_date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return startDate, endDate, errors.New("Invalid start date format")
+		}
+		startDate = d
+	}
+	if v := c.Query("end_date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return startDate, endDate, errors.New("Invalid end date format")
+		}
+		endDate = d
+	}
+	if endDate.Before(startDate) {
+		return startDate, endDate, errors.New("End date is before start date")
+	}
+
+	return startDate, endDate, nil
+}
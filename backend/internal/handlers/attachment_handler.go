@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"timetracker/internal/config"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/storage"
+	"timetracker/internal/utils"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+)
+
+// sniffLength is how much of an upload is read to detect its type.
+const sniffLength = 3072
+
+var attachmentSorts = listSorts{"created_at": "created_at", "file_name": "LOWER(file_name)", "size": "size"}
+
+// GetProjectAttachments lists the files on a project, newest first.
+// ?include_tasks=true adds the files on its tasks.
+func GetProjectAttachments(c *gin.Context) {
+	var project models.Project
+	if err := database.DB.Where("id = ? AND id IN (?)", c.Param("id"), memberProjectIDs(c, models.ProjectRoleViewer)).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	query := database.DB.Where("project_id = ?", project.ID)
+	if c.Query("include_tasks") != "true" {
+		query = query.Where("task_id IS NULL")
+	}
+
+	list, err := parseListQuery(c, "attachments", attachmentSorts, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var attachments []models.Attachment
+	if err := list.apply(query).Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching attachments"})
+		return
+	}
+	attachments = page(c, list, attachments, func(a models.Attachment) uint { return a.ID })
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func UploadProjectAttachment(c *gin.Context) {
+	var project models.Project
+	if err := database.DB.Where("id = ? AND id IN (?)", c.Param("id"), memberProjectIDs(c, models.ProjectRoleViewer)).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !hasProjectRole(c, project.ID, models.ProjectRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to add attachments to this project"})
+		return
+	}
+
+	saveAttachment(c, models.Attachment{
+		ProjectID:   project.ID,
+		WorkspaceID: project.WorkspaceID,
+	})
+}
+
+func GetTaskAttachments(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+
+	list, err := parseListQuery(c, "attachments", attachmentSorts, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var attachments []models.Attachment
+	if err := list.apply(database.DB.Where("task_id = ?", task.ID)).Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching attachments"})
+		return
+	}
+	attachments = page(c, list, attachments, func(a models.Attachment) uint { return a.ID })
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func UploadTaskAttachment(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	saveAttachment(c, models.Attachment{
+		ProjectID:   task.ProjectID,
+		TaskID:      &task.ID,
+		WorkspaceID: task.WorkspaceID,
+	})
+}
+
+// DownloadAttachment streams a file back. Files are always sent as downloads
+// with their sniffed type, so uploaded HTML or scripts never run in the app's
+// origin.
+func DownloadAttachment(c *gin.Context) {
+	var attachment models.Attachment
+	if err := database.DB.Where("id = ? AND project_id IN (?)", c.Param("id"), memberProjectIDs(c, models.ProjectRoleViewer)).First(&attachment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	content, err := storage.Files.Get(c.Request.Context(), attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment content is missing"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading attachment"})
+		return
+	}
+	defer content.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition":    disposition,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// DeleteAttachment removes a file. Uploaders may delete their own files,
+// project managers any file.
+func DeleteAttachment(c *gin.Context) {
+	var attachment models.Attachment
+	if err := database.DB.Where("id = ? AND project_id IN (?)", c.Param("id"), memberProjectIDs(c, models.ProjectRoleViewer)).First(&attachment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	if !hasProjectRole(c, attachment.ProjectID, models.ProjectRoleManager) &&
+		!(attachment.UserID == utils.GetUserID(c) && hasProjectRole(c, attachment.ProjectID, models.ProjectRoleMember)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to delete this attachment"})
+		return
+	}
+
+	if err := database.DB.Unscoped().Delete(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting attachment"})
+		return
+	}
+
+	// The record is gone, so a leftover object is only wasted space
+	if err := storage.Files.Delete(c.Request.Context(), attachment.StorageKey); err != nil {
+		log.Printf("Error deleting attachment content %s: %v", attachment.StorageKey, err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// saveAttachment stores the multipart "file" field of the request and records
+// it as attachment. The type is sniffed from the content rather than trusted
+// from the client, and uploads over MAX_UPLOAD_SIZE are refused.
+func saveAttachment(c *gin.Context, attachment models.Attachment) {
+	maxSize := config.AppConfig.MaxUploadSize
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File is larger than %d bytes", maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": `Upload the file in the "file" form field`})
+		return
+	}
+	if header.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File is larger than %d bytes", maxSize)})
+		return
+	}
+	if header.Size == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is empty"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading upload"})
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading upload"})
+		return
+	}
+	head = head[:n]
+
+	key, err := attachmentKey(attachment.WorkspaceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing file"})
+		return
+	}
+
+	attachment.FileName = header.Filename
+	attachment.ContentType = mimetype.Detect(head).String()
+	attachment.Size = header.Size
+	attachment.StorageKey = key
+	attachment.UserID = utils.GetUserID(c)
+
+	ctx := c.Request.Context()
+	if err := storage.Files.Put(ctx, key, io.MultiReader(bytes.NewReader(head), file), header.Size, attachment.ContentType); err != nil {
+		log.Printf("Error storing attachment %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing file"})
+		return
+	}
+
+	if err := database.DB.Create(&attachment).Error; err != nil {
+		storage.Files.Delete(ctx, key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating attachment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func attachmentKey(workspaceID uint) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("attachments/%d/%s", workspaceID, hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"timetracker/internal/config"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// memoryStorage keeps objects in memory.
+type memoryStorage struct {
+	mu           sync.Mutex
+	objects      map[string][]byte
+	contentTypes map[string]string
+}
+
+func (m *memoryStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	m.contentTypes[key] = contentType
+	return nil
+}
+
+func (m *memoryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memoryStorage) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+// TestSaveAttachment checks the upload limits and that the stored type is
+// sniffed from the content rather than taken from the name or the client.
+// The database runs in dry-run mode, so nothing is written.
+func TestSaveAttachment(t *testing.T) {
+	dryRun, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := &memoryStorage{objects: make(map[string][]byte), contentTypes: make(map[string]string)}
+
+	previousDB, previousFiles, previousMax := database.DB, storage.Files, config.AppConfig.MaxUploadSize
+	database.DB, storage.Files, config.AppConfig.MaxUploadSize = dryRun, files, 1024
+	t.Cleanup(func() {
+		database.DB, storage.Files, config.AppConfig.MaxUploadSize = previousDB, previousFiles, previousMax
+	})
+	gin.SetMode(gin.TestMode)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, 64)...)
+
+	tests := []struct {
+		name        string
+		field       string
+		fileName    string
+		content     []byte
+		status      int
+		contentType string
+	}{
+		{"png named as text", "file", "notes.txt", png, http.StatusCreated, "image/png"},
+		{"html named as image", "file", "photo.png", []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"), http.StatusCreated, "text/html; charset=utf-8"},
+		{"plain text", "file", "notes", []byte("Meeting notes\n"), http.StatusCreated, "text/plain; charset=utf-8"},
+		{"pdf", "file", "invoice", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"), http.StatusCreated, "application/pdf"},
+		{"at the limit", "file", "a.txt", bytes.Repeat([]byte("a"), 1024), http.StatusCreated, "text/plain; charset=utf-8"},
+		{"over the limit", "file", "a.txt", bytes.Repeat([]byte("a"), 1025), http.StatusRequestEntityTooLarge, ""},
+		{"body over the limit", "file", "a.txt", bytes.Repeat([]byte("a"), 2<<20), http.StatusRequestEntityTooLarge, ""},
+		{"empty", "file", "a.txt", nil, http.StatusBadRequest, ""},
+		{"wrong field", "upload", "a.txt", []byte("a"), http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, err := form.CreateFormFile(tt.field, tt.fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write(tt.content)
+			form.Close()
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/projects/1/attachments", &body)
+			c.Request.Header.Set("Content-Type", form.FormDataContentType())
+			c.Set("user_id", uint(1))
+
+			files.mu.Lock()
+			stored := len(files.objects)
+			files.mu.Unlock()
+
+			saveAttachment(c, models.Attachment{ProjectID: 1, WorkspaceID: 1})
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusCreated {
+				if len(files.objects) != stored {
+					t.Errorf("rejected upload was stored")
+				}
+				return
+			}
+
+			var attachment models.Attachment
+			if err := json.Unmarshal(w.Body.Bytes(), &attachment); err != nil {
+				t.Fatal(err)
+			}
+			if attachment.ContentType != tt.contentType {
+				t.Errorf("content type = %q, want %q", attachment.ContentType, tt.contentType)
+			}
+			if attachment.FileName != tt.fileName || attachment.Size != int64(len(tt.content)) {
+				t.Errorf("file = %q, %d bytes, want %q, %d bytes", attachment.FileName, attachment.Size, tt.fileName, len(tt.content))
+			}
+
+			files.mu.Lock()
+			defer files.mu.Unlock()
+			for key, data := range files.objects {
+				if !strings.HasPrefix(key, "attachments/1/") {
+					t.Errorf("stored under %q", key)
+				}
+				if bytes.Equal(data, tt.content) && files.contentTypes[key] == tt.contentType {
+					return
+				}
+			}
+			t.Errorf("content not stored with type %q", tt.contentType)
+		})
+	}
+}
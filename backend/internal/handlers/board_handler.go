@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MoveTaskRequest struct {
+	Status string `json:"status" binding:"required"`
+	// Position is the index in the target column. BeforeID, when set, places
+	// the task in front of that task instead, which stays correct when the
+	// client's view of the column is out of date.
+	Position *int `json:"position"`
+	BeforeID uint `json:"before_id"`
+}
+
+type BoardColumn struct {
+	Status string        `json:"status"`
+	Tasks  []models.Task `json:"tasks"`
+
+	// UnblockedTasks is set by MoveTask when the move completed the task
+	UnblockedTasks []models.Task `json:"unblocked_tasks,omitempty"`
+}
+
+var boardStatuses = []string{
+	models.TaskStatusTodo,
+	models.TaskStatusInProgress,
+	models.TaskStatusOnHold,
+	models.TaskStatusCompleted,
+}
+
+// GetProjectBoard returns the project's tasks grouped into status columns in
+// board order.
+func GetProjectBoard(c *gin.Context) {
+	projectID := c.Param("id")
+
+	if !hasProjectRole(c, projectID, models.ProjectRoleViewer) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var tasks []models.Task
+	err := database.DB.Preload("Tags").
+		Where("project_id = ?", projectID).
+		Order("position, id").
+		Find(&tasks).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+
+	if err := loadTaskDetails(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+
+	columns := make([]BoardColumn, len(boardStatuses))
+	index := make(map[string]int)
+	for i, status := range boardStatuses {
+		columns[i] = BoardColumn{Status: status, Tasks: []models.Task{}}
+		index[status] = i
+	}
+	for _, task := range tasks {
+		i := index[task.Status]
+		columns[i].Tasks = append(columns[i].Tasks, task)
+	}
+
+	c.JSON(http.StatusOK, columns)
+}
+
+// MoveTask changes a task's status and position in one step and returns the
+// target column in its new order. Moves within a project are serialized by
+// locking the project row, and the affected columns are renumbered, so
+// concurrent moves never leave duplicate or missing positions.
+func MoveTask(c *gin.Context) {
+	var req MoveTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var column []models.Task
+	var moveErr error
+	completing := false
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockTaskPositions(tx, task.ProjectID); err != nil {
+			return err
+		}
+
+		// Re-read under the lock, another move may have just changed it
+		if err := tx.First(&task, task.ID).Error; err != nil {
+			return err
+		}
+		oldStatus := task.Status
+
+		if req.Status != oldStatus {
+			if req.Status == models.TaskStatusCompleted && hasOpenSubtasks(task.ID) {
+				moveErr = errOpenSubtasks
+				return moveErr
+			}
+			if err := setTaskStatus(&task, req.Status); err != nil {
+				moveErr = err
+				return err
+			}
+			if err := saveTaskStatus(tx, &task, utils.GetUserID(c)); err != nil {
+				return err
+			}
+			completing = req.Status == models.TaskStatusCompleted
+		}
+
+		if err := tx.Where("project_id = ? AND status = ? AND id <> ?", task.ProjectID, task.Status, task.ID).
+			Order("position, id").
+			Find(&column).Error; err != nil {
+			return err
+		}
+
+		at := len(column)
+		if req.BeforeID != 0 {
+			for i, t := range column {
+				if t.ID == req.BeforeID {
+					at = i
+					break
+				}
+			}
+		} else if req.Position != nil && *req.Position >= 0 && *req.Position < len(column) {
+			at = *req.Position
+		}
+		task.Position = -1 // always write the moved task's position
+		column = append(column[:at], append([]models.Task{task}, column[at:]...)...)
+
+		if err := renumberColumn(tx, column); err != nil {
+			return err
+		}
+
+		if oldStatus != task.Status {
+			var previous []models.Task
+			if err := tx.Where("project_id = ? AND status = ?", task.ProjectID, oldStatus).
+				Order("position, id").
+				Find(&previous).Error; err != nil {
+				return err
+			}
+			if err := renumberColumn(tx, previous); err != nil {
+				return err
+			}
+			if completing {
+				if _, err := spawnNextInstance(tx, task.ID); err != nil {
+					return err
+				}
+			}
+			return syncParentStatus(tx, task.ParentID)
+		}
+		return nil
+	})
+	if moveErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": moveErr.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error moving task"})
+		return
+	}
+
+	response := BoardColumn{Status: task.Status, Tasks: column}
+	if completing {
+		response.UnblockedTasks, err = unblockedBy(task.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching unblocked tasks"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// renumberColumn stores the column's order as positions 0..n-1, writing only
+// the tasks whose position changed.
+func renumberColumn(tx *gorm.DB, column []models.Task) error {
+	for i := range column {
+		if column[i].Position == i {
+			continue
+		}
+		if err := tx.Model(&models.Task{}).Where("id = ?", column[i].ID).UpdateColumn("position", i).Error; err != nil {
+			return err
+		}
+		column[i].Position = i
+	}
+	return nil
+}
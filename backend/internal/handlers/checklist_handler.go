@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ChecklistItemRequest struct {
+	Title    string `json:"title"`
+	Done     *bool  `json:"done"`
+	Position *int   `json:"position"`
+}
+
+var checklistSorts = listSorts{"position": "position", "title": "LOWER(title)", "created_at": "created_at"}
+
+func GetChecklist(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+
+	list, err := parseListQuery(c, "task_checklist_items", checklistSorts, "position", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var items []models.TaskChecklistItem
+	if err := list.apply(database.DB.Where("task_id = ?", task.ID)).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching checklist"})
+		return
+	}
+	items = page(c, list, items, func(i models.TaskChecklistItem) uint { return i.ID })
+
+	c.JSON(http.StatusOK, items)
+}
+
+// CreateChecklistItem appends an item to the task's checklist unless a
+// position is given.
+func CreateChecklistItem(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var req ChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+
+	item := models.TaskChecklistItem{
+		Title:  req.Title,
+		TaskID: task.ID,
+	}
+	if req.Done != nil {
+		item.Done = *req.Done
+	}
+	if req.Position != nil {
+		item.Position = *req.Position
+	} else {
+		database.DB.Model(&models.TaskChecklistItem{}).
+			Select("COALESCE(MAX(position), -1) + 1").
+			Where("task_id = ?", task.ID).
+			Scan(&item.Position)
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		return syncParentStatus(tx, &task.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating checklist item"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+func UpdateChecklistItem(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var item models.TaskChecklistItem
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("itemId"), task.ID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return
+	}
+
+	var req ChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Title != "" {
+		item.Title = req.Title
+	}
+	if req.Done != nil {
+		item.Done = *req.Done
+	}
+	if req.Position != nil {
+		item.Position = *req.Position
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&item).Error; err != nil {
+			return err
+		}
+		return syncParentStatus(tx, &task.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating checklist item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func DeleteChecklistItem(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var rows int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND task_id = ?", c.Param("itemId"), task.ID).Delete(&models.TaskChecklistItem{})
+		if result.Error != nil {
+			return result.Error
+		}
+		rows = result.RowsAffected
+		return syncParentStatus(tx, &task.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting checklist item"})
+		return
+	}
+
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findTaskWithRole loads the task in the :id path parameter and checks that
+// the caller holds at least minRole on its project, writing the error response
+// otherwise.
+func findTaskWithRole(c *gin.Context, minRole string) (models.Task, bool) {
+	var task models.Task
+	if err := database.DB.Where("id = ? AND project_id IN (?)", c.Param("id"), memberProjectIDs(c, models.ProjectRoleViewer)).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return task, false
+	}
+
+	if !hasProjectRole(c, task.ProjectID, minRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to change this task"})
+		return task, false
+	}
+
+	return task, true
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+var clientSorts = listSorts{"name": "LOWER(name)", "created_at": "created_at"}
+
+func GetClients(c *gin.Context) {
+	list, err := parseListQuery(c, "clients", clientSorts, "name", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var clients []models.Client
+	if err := list.apply(database.DB.Where("workspace_id = ?", utils.GetWorkspaceID(c))).Find(&clients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching clients"})
+		return
+	}
+	clients = page(c, list, clients, func(cl models.Client) uint { return cl.ID })
+
+	c.JSON(http.StatusOK, clients)
+}
+
+func GetClient(c *gin.Context) {
+	clientID := c.Param("id")
+
+	var client models.Client
+	err := database.DB.Preload("Projects", "id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).
+		Where("id = ? AND workspace_id = ?", clientID, utils.GetWorkspaceID(c)).
+		First(&client).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+func CreateClient(c *gin.Context) {
+	var client models.Client
+	if err := c.ShouldBindJSON(&client); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client.UserID = utils.GetUserID(c)
+	client.WorkspaceID = utils.GetWorkspaceID(c)
+
+	if err := database.DB.Omit("Projects").Create(&client).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating client"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, client)
+}
+
+func UpdateClient(c *gin.Context) {
+	client, ok := findEditableClient(c)
+	if !ok {
+		return
+	}
+
+	var updates models.Client
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates.UserID = 0
+	updates.WorkspaceID = 0
+
+	if err := database.DB.Model(&client).Omit("Projects").Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating client"})
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+func DeleteClient(c *gin.Context) {
+	client, ok := findEditableClient(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(&models.Project{}).Where("client_id = ?", client.ID).Update("client_id", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting client"})
+		return
+	}
+
+	if err := database.DB.Delete(&client).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting client"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findEditableClient loads the client in the URL and checks the caller created
+// it or administers the workspace.
+func findEditableClient(c *gin.Context) (models.Client, bool) {
+	var client models.Client
+	if err := database.DB.Where("id = ? AND workspace_id = ?", c.Param("id"), utils.GetWorkspaceID(c)).First(&client).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return client, false
+	}
+
+	if client.UserID != utils.GetUserID(c) && !isWorkspaceAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to change this client"})
+		return client, false
+	}
+
+	return client, true
+}
+
+func clientInWorkspace(clientID, workspaceID uint) bool {
+	var count int64
+	database.DB.Model(&models.Client{}).
+		Where("id = ? AND workspace_id = ?", clientID, workspaceID).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CommentRequest struct {
+	Body string `json:"body"`
+}
+
+// CommentAuthor is what a comment shows of its author to every viewer of the
+// task.
+type CommentAuthor struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+}
+
+type CommentResponse struct {
+	models.TaskComment
+	User CommentAuthor `json:"user"`
+}
+
+const (
+	TaskActivityCreated      = "created"
+	TaskActivityComment      = "comment"
+	TaskActivityStatusChange = "status_change"
+	TaskActivityTimeEntry    = "time_entry"
+)
+
+// TaskActivity is one event in a task's activity feed. Exactly one of
+// Comment, StatusChange and TimeEntry is set, matching Type, except for the
+// created event which has none.
+type TaskActivity struct {
+	Type         string                   `json:"type"`
+	At           time.Time                `json:"at"`
+	UserID       *uint                    `json:"user_id"`
+	Comment      *CommentResponse         `json:"comment,omitempty"`
+	StatusChange *models.TaskStatusChange `json:"status_change,omitempty"`
+	TimeEntry    *models.TimeEntry        `json:"time_entry,omitempty"`
+}
+
+var commentSorts = listSorts{"created_at": "created_at"}
+
+func GetTaskComments(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+
+	list, err := parseListQuery(c, "task_comments", commentSorts, "created_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var comments []models.TaskComment
+	if err := list.apply(withCommentAuthors().Where("task_id = ?", task.ID)).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching comments"})
+		return
+	}
+	comments = page(c, list, comments, func(cm models.TaskComment) uint { return cm.ID })
+
+	response := make([]CommentResponse, len(comments))
+	for i, comment := range comments {
+		response[i] = commentResponse(comment)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func CreateTaskComment(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment body is required"})
+		return
+	}
+
+	comment := models.TaskComment{
+		Body:   req.Body,
+		TaskID: task.ID,
+		UserID: utils.GetUserID(c),
+	}
+	if err := database.DB.Create(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating comment"})
+		return
+	}
+	database.DB.Select("id", "email").First(&comment.User, comment.UserID)
+
+	c.JSON(http.StatusCreated, commentResponse(comment))
+}
+
+// UpdateTaskComment edits a comment. Only its author may edit it.
+func UpdateTaskComment(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var comment models.TaskComment
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("commentId"), task.ID).First(&comment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	if comment.UserID != utils.GetUserID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit a comment"})
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment body is required"})
+		return
+	}
+
+	if err := database.DB.Model(&comment).Update("body", req.Body).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating comment"})
+		return
+	}
+	database.DB.Select("id", "email").First(&comment.User, comment.UserID)
+
+	c.JSON(http.StatusOK, commentResponse(comment))
+}
+
+// DeleteTaskComment removes a comment. Authors may delete their own comments,
+// project managers any comment.
+func DeleteTaskComment(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var comment models.TaskComment
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("commentId"), task.ID).First(&comment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	if comment.UserID != utils.GetUserID(c) && !hasProjectRole(c, task.ProjectID, models.ProjectRoleManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to delete this comment"})
+		return
+	}
+
+	if err := database.DB.Delete(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting comment"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTaskActivity merges a task's creation, comments, status changes and the
+// time entries the caller can see into one feed, newest first.
+func GetTaskActivity(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+
+	var comments []models.TaskComment
+	if err := withCommentAuthors().Where("task_id = ?", task.ID).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching activity"})
+		return
+	}
+
+	var changes []models.TaskStatusChange
+	if err := database.DB.Where("task_id = ?", task.ID).Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching activity"})
+		return
+	}
+
+	var entries []models.TimeEntry
+	if err := visibleTimeEntries(c).Preload("Tags").Where("task_id = ?", task.ID).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching activity"})
+		return
+	}
+
+	activity := []TaskActivity{{Type: TaskActivityCreated, At: task.CreatedAt, UserID: &task.UserID}}
+	for i := range comments {
+		comment := commentResponse(comments[i])
+		activity = append(activity, TaskActivity{
+			Type:    TaskActivityComment,
+			At:      comment.CreatedAt,
+			UserID:  &comment.UserID,
+			Comment: &comment,
+		})
+	}
+	for i := range changes {
+		activity = append(activity, TaskActivity{
+			Type:         TaskActivityStatusChange,
+			At:           changes[i].CreatedAt,
+			UserID:       changes[i].UserID,
+			StatusChange: &changes[i],
+		})
+	}
+	for i := range entries {
+		activity = append(activity, TaskActivity{
+			Type:      TaskActivityTimeEntry,
+			At:        entries[i].StartTime,
+			UserID:    &entries[i].UserID,
+			TimeEntry: &entries[i],
+		})
+	}
+
+	sort.SliceStable(activity, func(i, j int) bool {
+		return activity[i].At.After(activity[j].At)
+	})
+
+	c.JSON(http.StatusOK, activity)
+}
+
+// withCommentAuthors loads only the author fields CommentAuthor shows.
+func withCommentAuthors() *gorm.DB {
+	return database.DB.Preload("User", func(db *gorm.DB) *gorm.DB { return db.Select("id", "email") })
+}
+
+func commentResponse(comment models.TaskComment) CommentResponse {
+	return CommentResponse{
+		TaskComment: comment,
+		User:        CommentAuthor{ID: comment.User.ID, Email: comment.User.Email},
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"timetracker/internal/models"
+)
+
+func TestCommentResponseAuthor(t *testing.T) {
+	workspaceID := uint(4)
+	comment := models.TaskComment{Body: "Looks good", TaskID: 2, UserID: 3}
+	comment.User = models.User{Email: "ann@example.com", BaseCurrency: "EUR", ActiveWorkspaceID: &workspaceID, CostRate: 80}
+	comment.User.ID = 3
+
+	data, err := json.Marshal(commentResponse(comment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"id": float64(3), "email": "ann@example.com"}
+	if !reflect.DeepEqual(got["user"], want) {
+		t.Errorf("user = %v, want %v", got["user"], want)
+	}
+	if got["body"] != "Looks good" || got["user_id"] != float64(3) {
+		t.Errorf("comment fields missing from %s", data)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CostRateRequest struct {
+	CostRate *float64 `json:"cost_rate" binding:"required"`
+}
+
+// MemberCostRate is what a member costs per hour. Cost rates are internal,
+// so only workspace admins and project managers get to see them.
+type MemberCostRate struct {
+	UserID          uint     `json:"user_id"`
+	Email           string   `json:"email"`
+	BaseCurrency    string   `json:"base_currency"`
+	CostRate        float64  `json:"cost_rate"`                   // in BaseCurrency
+	ProjectCostRate *float64 `json:"project_cost_rate,omitempty"` // override, in the project currency
+}
+
+// GetWorkspaceCostRates lists the cost rates of the workspace members.
+func GetWorkspaceCostRates(c *gin.Context) {
+	admin, ok := findWorkspaceMembership(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var rates []MemberCostRate
+	err := memberCostRates().
+		Joins("JOIN workspace_members ON workspace_members.user_id = users.id AND workspace_members.deleted_at IS NULL").
+		Where("workspace_members.workspace_id = ?", admin.WorkspaceID).
+		Scan(&rates).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching cost rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// UpdateWorkspaceCostRate sets the cost rate of a workspace member. Users
+// cannot set their own through /me.
+func UpdateWorkspaceCostRate(c *gin.Context) {
+	admin, ok := findWorkspaceMembership(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req CostRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.CostRate < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cost rate cannot be negative"})
+		return
+	}
+
+	var member models.WorkspaceMember
+	if err := database.DB.Where("workspace_id = ? AND user_id = ?", admin.WorkspaceID, c.Param("userId")).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", member.UserID).Update("cost_rate", *req.CostRate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating cost rate"})
+		return
+	}
+
+	var rate MemberCostRate
+	if err := memberCostRates().Where("users.id = ?", member.UserID).Scan(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching cost rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// GetProjectCostRates lists the cost rates of the project members with
+// their project overrides, which are set through UpdateProjectMember.
+func GetProjectCostRates(c *gin.Context) {
+	projectID := c.Param("id")
+
+	if !hasProjectRole(c, projectID, models.ProjectRoleManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to view cost rates"})
+		return
+	}
+
+	var rates []MemberCostRate
+	err := memberCostRates().
+		Select("users.id AS user_id, users.email, users.base_currency, users.cost_rate, project_members.cost_rate AS project_cost_rate").
+		Joins("JOIN project_members ON project_members.user_id = users.id AND project_members.deleted_at IS NULL").
+		Where("project_members.project_id = ?", projectID).
+		Scan(&rates).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching cost rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+func memberCostRates() *gorm.DB {
+	return database.DB.Model(&models.User{}).
+		Select("users.id AS user_id, users.email, users.base_currency, users.cost_rate").
+		Order("users.email")
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+var customFieldEntities = map[string]bool{
+	models.CustomFieldEntityProject:   true,
+	models.CustomFieldEntityTask:      true,
+	models.CustomFieldEntityTimeEntry: true,
+}
+
+var customFieldTypes = map[string]bool{
+	models.CustomFieldTypeText:   true,
+	models.CustomFieldTypeNumber: true,
+	models.CustomFieldTypeDate:   true,
+	models.CustomFieldTypeSelect: true,
+}
+
+var customFieldSorts = listSorts{"key": "key", "label": "LOWER(label)", "created_at": "created_at"}
+
+func GetCustomFields(c *gin.Context) {
+	list, err := parseListQuery(c, "custom_fields", customFieldSorts, "key", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := database.DB.Where("workspace_id = ?", utils.GetWorkspaceID(c))
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+
+	var fields []models.CustomField
+	if err := list.apply(query).Find(&fields).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching custom fields"})
+		return
+	}
+	fields = page(c, list, fields, func(f models.CustomField) uint { return f.ID })
+
+	c.JSON(http.StatusOK, fields)
+}
+
+func CreateCustomField(c *gin.Context) {
+	if !isWorkspaceAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage custom fields"})
+		return
+	}
+
+	var field models.CustomField
+	if err := c.ShouldBindJSON(&field); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !customFieldEntities[field.EntityType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entity_type must be project, task or time_entry"})
+		return
+	}
+	if !customFieldKeyPattern.MatchString(field.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key must be lowercase letters, digits and underscores"})
+		return
+	}
+	if err := validateCustomFieldDefinition(field); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if field.Label == "" {
+		field.Label = field.Key
+	}
+
+	field.UserID = utils.GetUserID(c)
+	field.WorkspaceID = utils.GetWorkspaceID(c)
+
+	if err := database.DB.Create(&field).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A custom field with this key already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, field)
+}
+
+// UpdateCustomField changes a field's label, options or required flag. The
+// key, entity type and value type are fixed once values may exist.
+func UpdateCustomField(c *gin.Context) {
+	if !isWorkspaceAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage custom fields"})
+		return
+	}
+
+	var field models.CustomField
+	if err := database.DB.Where("id = ? AND workspace_id = ?", c.Param("id"), utils.GetWorkspaceID(c)).First(&field).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return
+	}
+
+	var updates struct {
+		Label    string   `json:"label"`
+		Options  []string `json:"options"`
+		Required *bool    `json:"required"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updates.Label != "" {
+		field.Label = updates.Label
+	}
+	if updates.Options != nil {
+		field.Options = updates.Options
+	}
+	if updates.Required != nil {
+		field.Required = *updates.Required
+	}
+	if err := validateCustomFieldDefinition(field); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Save(&field).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating custom field"})
+		return
+	}
+
+	c.JSON(http.StatusOK, field)
+}
+
+// DeleteCustomField removes the definition. Stored values are left in place
+// but are no longer validated or exported, and are dropped the next time
+// their record is updated.
+func DeleteCustomField(c *gin.Context) {
+	if !isWorkspaceAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage custom fields"})
+		return
+	}
+
+	result := database.DB.Unscoped().Where("id = ? AND workspace_id = ?", c.Param("id"), utils.GetWorkspaceID(c)).Delete(&models.CustomField{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting custom field"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func validateCustomFieldDefinition(field models.CustomField) error {
+	if !customFieldTypes[field.Type] {
+		return fmt.Errorf("type must be text, number, date or select")
+	}
+	if field.Type == models.CustomFieldTypeSelect && len(field.Options) == 0 {
+		return fmt.Errorf("select fields need at least one option")
+	}
+	return nil
+}
+
+// loadCustomFields returns a workspace's field definitions for an entity type,
+// in key order.
+func loadCustomFields(workspaceID uint, entityType string) ([]models.CustomField, error) {
+	var fields []models.CustomField
+	err := database.DB.Where("workspace_id = ? AND entity_type = ?", workspaceID, entityType).
+		Order("key").
+		Find(&fields).Error
+	return fields, err
+}
+
+// validateCustomFields checks values against the workspace's definitions for
+// the entity type and returns them normalised: numbers as float64, dates as
+// YYYY-MM-DD strings. Unknown keys are rejected on create; on update they are
+// dropped, as they may be values of deleted definitions that clients echo
+// back.
+func validateCustomFields(c *gin.Context, entityType string, values models.JSONMap, updating bool) (models.JSONMap, error) {
+	fields, err := loadCustomFields(utils.GetWorkspaceID(c), entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]models.CustomField)
+	for _, f := range fields {
+		byKey[f.Key] = f
+	}
+
+	result := models.JSONMap{}
+	for key, value := range values {
+		field, ok := byKey[key]
+		if !ok && updating {
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field %q", key)
+		}
+		if value == nil || value == "" {
+			continue
+		}
+
+		normalized, err := normalizeCustomFieldValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = normalized
+	}
+
+	for _, f := range fields {
+		if _, ok := result[f.Key]; f.Required && !ok {
+			return nil, fmt.Errorf("custom field %q is required", f.Key)
+		}
+	}
+
+	return result, nil
+}
+
+func normalizeCustomFieldValue(field models.CustomField, value interface{}) (interface{}, error) {
+	switch field.Type {
+	case models.CustomFieldTypeText:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case models.CustomFieldTypeNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			if n, err := strconv.ParseFloat(v, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+				return n, nil
+			}
+		}
+	case models.CustomFieldTypeDate:
+		if s, ok := value.(string); ok {
+			if _, err := time.Parse("2006-01-02", s); err == nil {
+				return s, nil
+			}
+		}
+	case models.CustomFieldTypeSelect:
+		if s, ok := value.(string); ok {
+			for _, option := range field.Options {
+				if option == s {
+					return s, nil
+				}
+			}
+			return nil, fmt.Errorf("custom field %q must be one of %s", field.Key, strings.Join(field.Options, ", "))
+		}
+	}
+	return nil, fmt.Errorf("custom field %q must be a %s", field.Key, field.Type)
+}
+
+// applyCustomFieldFilters narrows a list query with ?cf.<key>=<value>
+// parameters, matching the stored value as text.
+func applyCustomFieldFilters(c *gin.Context, query *gorm.DB, table string) *gorm.DB {
+	for param, values := range c.Request.URL.Query() {
+		key, ok := strings.CutPrefix(param, "cf.")
+		if !ok || !customFieldKeyPattern.MatchString(key) || len(values) == 0 {
+			continue
+		}
+		query = query.Where(table+".custom_fields ->> ? = ?", key, values[0])
+	}
+	return query
+}
+
+// formatCustomFieldValue renders a stored value for invoices and exports.
+func formatCustomFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
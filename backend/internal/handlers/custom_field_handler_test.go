@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+)
+
+func TestNormalizeCustomFieldValue(t *testing.T) {
+	number := models.CustomField{Key: "hours", Type: models.CustomFieldTypeNumber}
+	date := models.CustomField{Key: "signed", Type: models.CustomFieldTypeDate}
+	choice := models.CustomField{Key: "tier", Type: models.CustomFieldTypeSelect, Options: []string{"gold", "silver"}}
+	text := models.CustomField{Key: "ref", Type: models.CustomFieldTypeText}
+
+	tests := []struct {
+		field models.CustomField
+		value interface{}
+		want  interface{}
+		ok    bool
+	}{
+		{number, 2.5, 2.5, true},
+		{number, "2.5", 2.5, true},
+		{number, "NaN", nil, false},
+		{number, "Inf", nil, false},
+		{number, "-infinity", nil, false},
+		{number, "1e400", nil, false},
+		{number, "two", nil, false},
+		{date, "2024-02-29", "2024-02-29", true},
+		{date, "2023-02-29", nil, false},
+		{choice, "gold", "gold", true},
+		{choice, "bronze", nil, false},
+		{text, "A-1", "A-1", true},
+		{text, 1.0, nil, false},
+	}
+	for _, tt := range tests {
+		got, err := normalizeCustomFieldValue(tt.field, tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("normalizeCustomFieldValue(%s, %v) error = %v, want ok %v", tt.field.Type, tt.value, err, tt.ok)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeCustomFieldValue(%s, %v) = %v, want %v", tt.field.Type, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateAfterCustomFieldDeleted(t *testing.T) {
+	user, project := testProject(t)
+
+	field := models.CustomField{
+		EntityType:  models.CustomFieldEntityProject,
+		Key:         "po_number",
+		Type:        models.CustomFieldTypeText,
+		WorkspaceID: project.WorkspaceID,
+		UserID:      user.ID,
+	}
+	if err := database.DB.Create(&field).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Model(&project).Update("custom_fields", models.JSONMap{"po_number": "PO-7"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	c, w := testContext(user, http.MethodDelete, fmt.Sprintf("/custom-fields/%d", field.ID), nil, "id", fmt.Sprint(field.ID))
+	DeleteCustomField(c)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DeleteCustomField: %d %s", w.Code, w.Body)
+	}
+
+	c, w = testContext(user, http.MethodPut, fmt.Sprintf("/projects/%d", project.ID), strings.NewReader(`{"name": "Renamed"}`), "id", fmt.Sprint(project.ID))
+	UpdateProject(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("UpdateProject: %d %s", w.Code, w.Body)
+	}
+
+	c, w = testContext(user, http.MethodPost, "/projects", strings.NewReader(`{"name": "New", "custom_fields": {"po_number": "PO-8"}}`))
+	CreateProject(c)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("CreateProject with a deleted field: %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
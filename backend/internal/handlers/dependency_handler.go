@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DependencyRequest struct {
+	BlockedByID uint `json:"blocked_by_id" binding:"required"`
+}
+
+type DependenciesResponse struct {
+	BlockedBy []models.Task `json:"blocked_by"`
+	Blocks    []models.Task `json:"blocks"`
+}
+
+// GetTaskDependencies lists the tasks blocking the task and the tasks it
+// blocks. Both lists take ?sort= and ?order= like the task list, but as there
+// are two of them they are not paginated.
+func GetTaskDependencies(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+
+	list, err := parseListQuery(c, "tasks", taskSorts, "created_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if list.limit != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dependencies cannot be paginated"})
+		return
+	}
+
+	visible := memberProjectIDs(c, models.ProjectRoleViewer)
+	response := DependenciesResponse{}
+
+	err = list.apply(database.DB).Where("project_id IN (?) AND id IN (?)", visible,
+		database.DB.Model(&models.TaskDependency{}).Select("blocked_by_id").Where("task_id = ?", task.ID)).
+		Find(&response.BlockedBy).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching dependencies"})
+		return
+	}
+
+	err = list.apply(database.DB).Where("project_id IN (?) AND id IN (?)", visible,
+		database.DB.Model(&models.TaskDependency{}).Select("task_id").Where("blocked_by_id = ?", task.ID)).
+		Find(&response.Blocks).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching dependencies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AddTaskDependency records that the task is blocked by another task in the
+// workspace, rejecting dependencies that would form a cycle.
+func AddTaskDependency(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var req DependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var blocker models.Task
+	if err := database.DB.Where("id = ? AND project_id IN (?)", req.BlockedByID, memberProjectIDs(c, models.ProjectRoleViewer)).First(&blocker).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blocking task not found"})
+		return
+	}
+
+	if blocker.ID == task.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dependency would create a cycle"})
+		return
+	}
+
+	dependency := models.TaskDependency{TaskID: task.ID, BlockedByID: blocker.ID}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the workspace serializes dependency changes, as a cycle
+		// can be closed through tasks other than these two. The tasks are
+		// locked so neither is deleted before the dependency is added.
+		if err := tx.Clauses(clause.Locking{Strength: "NO KEY UPDATE"}).First(&models.Workspace{}, task.WorkspaceID).Error; err != nil {
+			return err
+		}
+		var locked []models.Task
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{task.ID, blocker.ID}).Order("id").Find(&locked).Error
+		if err != nil {
+			return err
+		}
+		if len(locked) != 2 {
+			return gorm.ErrRecordNotFound
+		}
+
+		cycle, err := blockedByChain(tx, blocker.ID, task.ID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return errDependencyCycle
+		}
+		if err := tx.Create(&dependency).Error; err != nil {
+			return errDependencyExists
+		}
+		return nil
+	})
+	if errors.Is(err, errDependencyCycle) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dependency would create a cycle"})
+		return
+	}
+	if errors.Is(err, errDependencyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Dependency already exists"})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error adding dependency"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dependency)
+}
+
+func RemoveTaskDependency(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Unscoped().
+		Where("task_id = ? AND blocked_by_id = ?", task.ID, c.Param("blockedById")).
+		Delete(&models.TaskDependency{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting dependency"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dependency not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+var (
+	errDependencyCycle  = errors.New("dependency would create a cycle")
+	errDependencyExists = errors.New("dependency already exists")
+)
+
+// blockedByChain reports whether taskID is, directly or transitively, blocked
+// by blockerID.
+func blockedByChain(tx *gorm.DB, taskID, blockerID uint) (bool, error) {
+	var count int64
+	err := tx.Raw(`
+		WITH RECURSIVE chain AS (
+			SELECT blocked_by_id AS id FROM task_dependencies WHERE task_id = ? AND deleted_at IS NULL
+			UNION
+			SELECT d.blocked_by_id FROM task_dependencies d JOIN chain ON d.task_id = chain.id WHERE d.deleted_at IS NULL
+		)
+		SELECT COUNT(*) FROM chain WHERE id = ?`, taskID, blockerID).Scan(&count).Error
+	return count > 0, err
+}
+
+// openBlockers is a subquery selecting the dependencies whose blocking task is
+// not completed yet.
+func openBlockers() *gorm.DB {
+	return database.DB.Model(&models.TaskDependency{}).
+		Joins("JOIN tasks blockers ON blockers.id = task_dependencies.blocked_by_id AND blockers.deleted_at IS NULL").
+		Where("blockers.status <> ?", models.TaskStatusCompleted)
+}
+
+// loadTaskBlocked flags tasks that wait on an open blocking task.
+func loadTaskBlocked(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	var blocked []uint
+	err := openBlockers().
+		Distinct("task_dependencies.task_id").
+		Where("task_dependencies.task_id IN ?", ids).
+		Pluck("task_dependencies.task_id", &blocked).Error
+	if err != nil {
+		return err
+	}
+
+	isBlocked := make(map[uint]bool)
+	for _, id := range blocked {
+		isBlocked[id] = true
+	}
+	for i := range tasks {
+		tasks[i].Blocked = isBlocked[tasks[i].ID]
+	}
+	return nil
+}
+
+// unblockedBy returns the tasks that were waiting on taskID and have no open
+// blockers left.
+func unblockedBy(taskID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := database.DB.
+		Where("id IN (?)", database.DB.Model(&models.TaskDependency{}).Select("task_id").Where("blocked_by_id = ?", taskID)).
+		Where("id NOT IN (?)", openBlockers().Select("task_dependencies.task_id")).
+		Find(&tasks).Error
+	return tasks, err
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+)
+
+// TestAddTaskDependencyConcurrent adds opposite dependencies between two
+// tasks at once; only one of them may succeed.
+func TestAddTaskDependencyConcurrent(t *testing.T) {
+	user, project := testProject(t)
+
+	tasks := make([]models.Task, 2)
+	for i := range tasks {
+		tasks[i] = models.Task{Title: fmt.Sprintf("Task %d", i), Status: models.TaskStatusTodo, ProjectID: project.ID, WorkspaceID: project.WorkspaceID, UserID: user.ID}
+		if err := database.DB.Create(&tasks[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range tasks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			task, blocker := tasks[i], tasks[1-i]
+			body := fmt.Sprintf(`{"blocked_by_id": %d}`, blocker.ID)
+			c, w := testContext(user, http.MethodPost, fmt.Sprintf("/tasks/%d/dependencies", task.ID), strings.NewReader(body), "id", fmt.Sprint(task.ID))
+			AddTaskDependency(c)
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			created++
+		} else if code != http.StatusBadRequest {
+			t.Errorf("AddTaskDependency: %d", code)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d of the opposite dependencies were added, want 1", created)
+	}
+}
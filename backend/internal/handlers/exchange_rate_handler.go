@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"timetracker/internal/config"
+	"timetracker/internal/database"
+	"timetracker/internal/exchange"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRateRequest struct {
+	Date          string  `json:"date" binding:"required"`
+	BaseCurrency  string  `json:"base_currency" binding:"required"`
+	QuoteCurrency string  `json:"quote_currency" binding:"required"`
+	Rate          float64 `json:"rate" binding:"required,gt=0"`
+}
+
+var exchangeRateSorts = listSorts{"date": "date", "created_at": "created_at"}
+
+func GetExchangeRates(c *gin.Context) {
+	list, err := parseListQuery(c, "exchange_rates", exchangeRateSorts, "date", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := database.DB.Where("workspace_id = ?", utils.GetWorkspaceID(c))
+	if base := c.Query("base"); base != "" {
+		query = query.Where("base_currency = ?", strings.ToUpper(base))
+	}
+	if quote := c.Query("quote"); quote != "" {
+		query = query.Where("quote_currency = ?", strings.ToUpper(quote))
+	}
+
+	var rates []models.ExchangeRate
+	if err := list.apply(query).Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching exchange rates"})
+		return
+	}
+	rates = page(c, list, rates, func(r models.ExchangeRate) uint { return r.ID })
+
+	c.JSON(http.StatusOK, rates)
+}
+
+func CreateExchangeRate(c *gin.Context) {
+	var req ExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		return
+	}
+	base, err := exchange.NormalizeCurrency(req.BaseCurrency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	quote, err := exchange.NormalizeCurrency(req.QuoteCurrency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate := models.ExchangeRate{
+		Date:          date,
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Rate:          req.Rate,
+		WorkspaceID:   utils.GetWorkspaceID(c),
+		UserID:        utils.GetUserID(c),
+	}
+	if err := saveExchangeRates([]models.ExchangeRate{rate}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// ImportExchangeRates accepts a multipart "file" holding either a CSV of
+// date,base,quote,rate rows or an ECB reference rate XML document. The format
+// is taken from ?format=csv|xml or, failing that, the file extension. When
+// the file gives a day and currency pair more than once, the last rate wins.
+func ImportExchangeRates(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+		return
+	}
+	defer file.Close()
+
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	var parsed []exchange.Rate
+	switch format {
+	case "csv":
+		parsed, err = exchange.ParseCSV(file)
+	case "xml", "ecb":
+		parsed, err = exchange.ParseECBXML(file)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected csv or xml"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspaceID, userID := utils.GetWorkspaceID(c), utils.GetUserID(c)
+	rates := make([]models.ExchangeRate, 0, len(parsed))
+	index := make(map[[3]string]int)
+	for _, r := range parsed {
+		rate := models.ExchangeRate{
+			Date:          r.Date,
+			BaseCurrency:  r.Base,
+			QuoteCurrency: r.Quote,
+			Rate:          r.Rate,
+			WorkspaceID:   workspaceID,
+			UserID:        userID,
+		}
+
+		// One upsert cannot touch a row twice
+		key := [3]string{r.Date.Format("2006-01-02"), r.Base, r.Quote}
+		if i, ok := index[key]; ok {
+			rates[i] = rate
+			continue
+		}
+		index[key] = len(rates)
+		rates = append(rates, rate)
+	}
+
+	if err := saveExchangeRates(rates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": len(rates)})
+}
+
+// DeleteExchangeRate removes a rate. Rates are shared by the workspace, so
+// only their creator or a workspace admin may delete them.
+func DeleteExchangeRate(c *gin.Context) {
+	query := database.DB.Unscoped().Where("id = ? AND workspace_id = ?", c.Param("id"), utils.GetWorkspaceID(c))
+	if !isWorkspaceAdmin(c) {
+		query = query.Where("user_id = ?", utils.GetUserID(c))
+	}
+
+	result := query.Delete(&models.ExchangeRate{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting exchange rate"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// saveExchangeRates inserts rates, replacing any existing rate of the
+// workspace for the same day and currency pair. rates must not repeat a day
+// and pair.
+func saveExchangeRates(rates []models.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "workspace_id"}, {Name: "date"}, {Name: "base_currency"}, {Name: "quote_currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "user_id", "updated_at"}),
+	}).CreateInBatches(&rates, 500).Error
+}
+
+// loadConverter builds a currency converter from the workspace's exchange
+// rates.
+func loadConverter(workspaceID uint) (*exchange.Converter, error) {
+	var rates []models.ExchangeRate
+	if err := database.DB.Where("workspace_id = ?", workspaceID).Find(&rates).Error; err != nil {
+		return nil, err
+	}
+
+	parsed := make([]exchange.Rate, len(rates))
+	for i, r := range rates {
+		parsed[i] = exchange.Rate{
+			Date:  r.Date,
+			Base:  r.BaseCurrency,
+			Quote: r.QuoteCurrency,
+			Rate:  r.Rate,
+		}
+	}
+
+	return exchange.NewConverter(parsed, config.AppConfig.ExchangePivotCurrency), nil
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"timetracker/internal/config"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testDBOnce sync.Once
+
+// testDB points database.DB at the Postgres database named by
+// TEST_DATABASE_URL, migrating it on first use, and skips the test when the
+// variable is not set. Every test creates its own user, so tests can share
+// the database.
+func testDB(t *testing.T) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	testDBOnce.Do(func() {
+		gin.SetMode(gin.TestMode)
+		config.AppConfig.DatabaseURL = url
+		database.InitDB()
+	})
+}
+
+var testUsers atomic.Int64
+
+// testProject creates a user and a project in their personal workspace.
+func testProject(t *testing.T) (models.User, models.Project) {
+	t.Helper()
+	testDB(t)
+
+	user := models.User{Email: fmt.Sprintf("%s-%d-%d@example.com", t.Name(), os.Getpid(), testUsers.Add(1))}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	project := models.Project{Name: t.Name(), WorkspaceID: *user.ActiveWorkspaceID, UserID: user.ID}
+	if err := database.DB.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user, project
+}
+
+// testContext builds the context of a request by user as the auth
+// middleware leaves it, with the route parameters given as name, value pairs.
+func testContext(user models.User, method, target string, body io.Reader, params ...string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, body)
+	c.Request.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(params); i += 2 {
+		c.Params = append(c.Params, gin.Param{Key: params[i], Value: params[i+1]})
+	}
+	c.Set("user_id", user.ID)
+	c.Set("workspace_id", *user.ActiveWorkspaceID)
+	c.Set("workspace_role", models.WorkspaceRoleOwner)
+	return c, w
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/taskimport"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxImportedTasks caps the number of tasks one import may create.
+const maxImportedTasks = 1000
+
+// ImportedTask is a task read from an import file. Parent is the index of
+// the task it is a subtask of within the import.
+type ImportedTask struct {
+	Line   int         `json:"line"`
+	Parent *int        `json:"parent,omitempty"`
+	Task   models.Task `json:"task"`
+}
+
+type ImportTasksResponse struct {
+	Preview bool           `json:"preview"`
+	Tasks   []ImportedTask `json:"tasks"`
+}
+
+// ImportTasks creates tasks in a project from a multipart "file" holding a
+// CSV file or a Markdown checklist. The format is taken from
+// ?format=csv|markdown or, failing that, the file extension. Markdown
+// headings become tags, ticked items completed tasks and nested items
+// subtasks. With ?preview=true the parsed tasks are returned without
+// creating anything.
+func ImportTasks(c *gin.Context) {
+	var project models.Project
+	if err := database.DB.Where("id = ? AND id IN (?)", c.Param("id"), memberProjectIDs(c, models.ProjectRoleViewer)).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !hasProjectRole(c, project.ID, models.ProjectRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to add tasks to this project"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+		return
+	}
+	defer file.Close()
+
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	var items []taskimport.Item
+	switch format {
+	case "csv":
+		items, err = taskimport.ParseCSV(file)
+	case "md", "markdown", "txt":
+		items, err = taskimport.ParseMarkdown(file)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected csv or markdown"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(items) > maxImportedTasks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot import more than %d tasks at once", maxImportedTasks)})
+		return
+	}
+
+	imported, err := importedTasks(c, project, items)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("preview") == "true" {
+		c.JSON(http.StatusOK, ImportTasksResponse{Preview: true, Tasks: imported})
+		return
+	}
+
+	var importErr error
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockTaskPositions(tx, project.ID); err != nil {
+			return err
+		}
+
+		// Tags are resolved only now, as resolving creates the missing ones
+		for i := range imported {
+			tags, err := resolveTags(tx, c, imported[i].Task.Tags)
+			if err != nil {
+				importErr = fmt.Errorf("line %d: %v", imported[i].Line, err)
+				return importErr
+			}
+			imported[i].Task.Tags = tags
+		}
+
+		for i := range imported {
+			task := &imported[i].Task
+			if parent := imported[i].Parent; parent != nil {
+				task.ParentID = &imported[*parent].Task.ID
+			}
+			if err := nextTaskPosition(tx, task.ProjectID, task.Status).Scan(&task.Position).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(task).Error; err != nil {
+				return err
+			}
+		}
+
+		// Parents follow their subtasks, deepest first
+		for i := len(imported) - 1; i >= 0; i-- {
+			if err := syncParentStatus(tx, imported[i].Task.ParentID); err != nil {
+				return err
+			}
+		}
+		for _, t := range imported {
+			if t.Parent == nil {
+				continue
+			}
+			parent := &imported[*t.Parent].Task
+			if err := tx.Select("status", "started_at", "completed_at", "position").First(parent, parent.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if importErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": importErr.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing tasks"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ImportTasksResponse{Tasks: imported})
+}
+
+// importedTasks validates parsed items and turns them into unsaved tasks of
+// project, with tags given by name only.
+func importedTasks(c *gin.Context, project models.Project, items []taskimport.Item) ([]ImportedTask, error) {
+	imported := make([]ImportedTask, len(items))
+	for i, item := range items {
+		task := models.Task{
+			Title:          item.Title,
+			Description:    item.Description,
+			DueDate:        item.DueDate,
+			EstimatedHours: item.EstimatedHours,
+			Priority:       strings.ToUpper(item.Priority),
+			ProjectID:      project.ID,
+			WorkspaceID:    project.WorkspaceID,
+			UserID:         utils.GetUserID(c),
+			Tags:           []models.Tag{},
+		}
+
+		if task.Priority == "" {
+			task.Priority = models.TaskPriorityMedium
+		}
+		if taskPriorityRank[task.Priority] == 0 {
+			return nil, fmt.Errorf("line %d: priority must be LOW, MEDIUM, HIGH or URGENT", item.Line)
+		}
+		if math.IsNaN(task.EstimatedHours) || math.IsInf(task.EstimatedHours, 0) {
+			return nil, fmt.Errorf("line %d: invalid estimated hours", item.Line)
+		}
+		if task.EstimatedHours < 0 {
+			return nil, fmt.Errorf("line %d: estimated hours cannot be negative", item.Line)
+		}
+
+		status := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(item.Status), " ", "_"))
+		switch {
+		case item.Done:
+			status = models.TaskStatusCompleted
+		case status == "":
+			status = models.TaskStatusTodo
+		}
+		if err := setTaskStatus(&task, status); err != nil {
+			return nil, fmt.Errorf("line %d: %v", item.Line, err)
+		}
+
+		for _, name := range item.Tags {
+			tag := models.Tag{Name: normalizeTagName(name), Color: models.DefaultTagColor}
+			if err := validateTag(tag); err != nil {
+				return nil, fmt.Errorf("line %d: %v", item.Line, err)
+			}
+			task.Tags = append(task.Tags, tag)
+		}
+
+		imported[i] = ImportedTask{Line: item.Line, Task: task}
+		if item.Parent >= 0 {
+			parent := item.Parent
+			imported[i].Parent = &parent
+		}
+	}
+	return imported, nil
+}
@@ -2,37 +2,74 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 	"timetracker/internal/database"
 	"timetracker/internal/models"
 	"timetracker/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InvoiceRequest struct {
 	ProjectID uint   `json:"projectId"` // Changed to match frontend
 	StartDate string `json:"startDate"` // Changed to string
 	EndDate   string `json:"endDate"`   // Changed to string
+
+	IncludeSubprojects bool `json:"includeSubprojects"`
 }
 
 type InvoiceEntry struct {
-	Date        string  `json:"date"`
+	Date        string            `json:"date"`
+	Hours       float64           `json:"hours"`
+	Description string            `json:"description,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+}
+
+// InvoiceProjectLine is the share of one project in an invoice that includes
+// sub-projects. Amount is in the invoice currency.
+type InvoiceProjectLine struct {
+	ProjectID   uint    `json:"projectId"`
+	ProjectName string  `json:"projectName"`
 	Hours       float64 `json:"hours"`
-	Description string  `json:"description,omitempty"`
+	HourlyRate  float64 `json:"hourlyRate"`
+	Currency    string  `json:"currency"`
+	Amount      float64 `json:"amount"`
+}
+
+// InvoiceColumn describes a time entry custom field shown in InvoiceEntry.Fields.
+type InvoiceColumn struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
 }
 
 type InvoiceResponse struct {
 	ProjectName string         `json:"projectName"`
+	Milestone   string         `json:"milestone,omitempty"`
 	StartDate   string         `json:"startDate"`
 	EndDate     string         `json:"endDate"`
 	TotalHours  float64        `json:"totalHours"`
 	HourlyRate  float64        `json:"hourlyRate"`
+	Currency    string         `json:"currency"`
 	TotalAmount float64        `json:"totalAmount"`
 	Entries     []InvoiceEntry `json:"entries"`
+
+	Projects     []InvoiceProjectLine `json:"projects,omitempty"`
+	CustomFields map[string]string    `json:"customFields,omitempty"`
+	Columns      []InvoiceColumn      `json:"columns,omitempty"`
 }
 
+// GenerateInvoice bills a project's time entries in the date range. With
+// includeSubprojects the entries of every sub-project the caller manages are
+// added, each billed at its own project's rate and converted into the
+// invoice project's currency. Nothing is stored; see CreateInvoice.
 func GenerateInvoice(c *gin.Context) {
 	var req InvoiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -40,73 +77,659 @@ func GenerateInvoice(c *gin.Context) {
 		return
 	}
 
+	response, _, ok := buildInvoice(c, req)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// buildInvoice computes the invoice GenerateInvoice describes, writing the
+// error response when it cannot.
+func buildInvoice(c *gin.Context, req InvoiceRequest) (*InvoiceResponse, models.Project, bool) {
+	var project models.Project
+
 	// Parse dates
 	startDate, err := time.Parse("2006-01-02", req.StartDate)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
-		return
+		return nil, project, false
 	}
 
 	endDate, err := time.Parse("2006-01-02", req.EndDate)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format"})
-		return
+		return nil, project, false
 	}
 
 	// Add one day to endDate to include the entire last day
 	endDate = endDate.Add(24 * time.Hour)
 
-	userID := utils.GetUserID(c)
-
-	var project models.Project
-	if err := database.DB.Where("id = ? AND user_id = ?", req.ProjectID, userID).First(&project).Error; err != nil {
+	if err := database.DB.Where("id = ? AND id IN (?)", req.ProjectID, memberProjectIDs(c, models.ProjectRoleManager)).First(&project).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
-		return
+		return nil, project, false
+	}
+
+	projects := []models.Project{project}
+	if req.IncludeSubprojects {
+		subtree, err := projectSubtreeIDs(project.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching projects"})
+			return nil, project, false
+		}
+		err = database.DB.Where("id IN ? AND id <> ? AND id IN (?)", subtree, project.ID, memberProjectIDs(c, models.ProjectRoleManager)).
+			Order("name").
+			Find(&projects).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching projects"})
+			return nil, project, false
+		}
+		projects = append([]models.Project{project}, projects...)
+	}
+
+	projectIDs := make([]uint, len(projects))
+	for i, p := range projects {
+		projectIDs[i] = p.ID
 	}
 
 	var entries []models.TimeEntry
 	err = database.DB.Where(
-		"project_id = ? AND user_id = ? AND start_time BETWEEN ? AND ?",
-		req.ProjectID, userID, startDate, endDate,
+		"project_id IN ? AND start_time BETWEEN ? AND ?",
+		projectIDs, startDate, endDate,
 	).Find(&entries).Error
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching time entries"})
-		return
+		return nil, project, false
 	}
 
-	// Group entries by date
+	formattedEntries, totalHours := groupEntriesByDate(entries)
+	totalAmount := totalHours * project.HourlyRate
+
+	var lines []InvoiceProjectLine
+	if req.IncludeSubprojects {
+		lines, totalAmount, err = invoiceProjectLines(project.WorkspaceID, project.Currency, projects, entries)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, project, false
+		}
+	}
+
+	response := &InvoiceResponse{
+		ProjectName: project.Name,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		TotalHours:  totalHours,
+		HourlyRate:  project.HourlyRate,
+		Currency:    project.Currency,
+		TotalAmount: totalAmount,
+		Entries:     formattedEntries,
+		Projects:    lines,
+	}
+	if err := addInvoiceCustomFields(response, project); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching custom fields"})
+		return nil, project, false
+	}
+
+	return response, project, true
+}
+
+// groupEntriesByDate sums time entries per day and returns them sorted by date
+// together with the total number of hours. Custom field values of the day's
+// entries are listed in Fields, distinct values joined by commas.
+func groupEntriesByDate(entries []models.TimeEntry) ([]InvoiceEntry, float64) {
 	entriesByDate := make(map[string]float64)
+	fieldsByDate := make(map[string]map[string][]string)
 	for _, entry := range entries {
 		date := entry.StartTime.Format("2006-01-02")
 		entriesByDate[date] += float64(entry.Duration) / 3600 // Convert seconds to hours
+
+		for key, value := range entry.CustomFields {
+			text := formatCustomFieldValue(value)
+			if text == "" {
+				continue
+			}
+			if fieldsByDate[date] == nil {
+				fieldsByDate[date] = make(map[string][]string)
+			}
+			if !containsString(fieldsByDate[date][key], text) {
+				fieldsByDate[date][key] = append(fieldsByDate[date][key], text)
+			}
+		}
 	}
 
-	// Create formatted entries
 	var formattedEntries []InvoiceEntry
+	var totalHours float64
 	for date, hours := range entriesByDate {
-		formattedEntries = append(formattedEntries, InvoiceEntry{
+		entry := InvoiceEntry{
 			Date:  date,
 			Hours: hours,
+		}
+		if len(fieldsByDate[date]) > 0 {
+			entry.Fields = make(map[string]string)
+			for key, values := range fieldsByDate[date] {
+				entry.Fields[key] = strings.Join(values, ", ")
+			}
+		}
+		formattedEntries = append(formattedEntries, entry)
+		totalHours += hours
+	}
+	sort.Slice(formattedEntries, func(i, j int) bool {
+		return formattedEntries[i].Date < formattedEntries[j].Date
+	})
+
+	return formattedEntries, totalHours
+}
+
+// addInvoiceCustomFields copies the project's custom field values onto the
+// invoice and lists the time entry fields available as entry columns.
+func addInvoiceCustomFields(invoice *InvoiceResponse, project models.Project) error {
+	if len(project.CustomFields) > 0 {
+		invoice.CustomFields = make(map[string]string)
+		for key, value := range project.CustomFields {
+			invoice.CustomFields[key] = formatCustomFieldValue(value)
+		}
+	}
+
+	fields, err := loadCustomFields(project.WorkspaceID, models.CustomFieldEntityTimeEntry)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		invoice.Columns = append(invoice.Columns, InvoiceColumn{Key: f.Key, Label: f.Label})
+	}
+
+	return nil
+}
+
+// invoiceProjectLines bills each project's entries at its own rate, converted
+// into currency at the date of the work, and returns the lines with the total.
+func invoiceProjectLines(workspaceID uint, currency string, projects []models.Project, entries []models.TimeEntry) ([]InvoiceProjectLine, float64, error) {
+	converter, err := loadConverter(workspaceID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	lines := make([]InvoiceProjectLine, len(projects))
+	index := make(map[uint]int)
+	for i, p := range projects {
+		lines[i] = InvoiceProjectLine{
+			ProjectID:   p.ID,
+			ProjectName: p.Name,
+			HourlyRate:  p.HourlyRate,
+			Currency:    p.Currency,
+		}
+		index[p.ID] = i
+	}
+
+	var total float64
+	for _, entry := range entries {
+		line := &lines[index[entry.ProjectID]]
+		hours := float64(entry.Duration) / 3600
+		amount, err := converter.Convert(hours*line.HourlyRate, line.Currency, currency, entry.StartTime)
+		if err != nil {
+			return nil, 0, fmt.Errorf("No exchange rate from %s to %s on %s", line.Currency, currency, entry.StartTime.Format("2006-01-02"))
+		}
+		line.Hours += hours
+		line.Amount += amount
+		total += amount
+	}
+
+	return lines, total, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateInvoiceRequest struct {
+	InvoiceRequest
+	MilestoneID      *uint  `json:"milestoneId"` // bills a completed milestone instead of a period
+	Notes            string `json:"notes"`
+	PaymentTermsDays *int   `json:"paymentTermsDays"` // default 30
+}
+
+type UpdateInvoiceRequest struct {
+	StartDate          *string `json:"startDate"`
+	EndDate            *string `json:"endDate"`
+	IncludeSubprojects *bool   `json:"includeSubprojects"`
+	Notes              *string `json:"notes"`
+	PaymentTermsDays   *int    `json:"paymentTermsDays"`
+}
+
+var invoiceSorts = listSorts{
+	"created_at":   "created_at",
+	"number":       "number",
+	"start_date":   "start_date",
+	"issued_at":    "issued_at",
+	"total_amount": "total_amount",
+	"due_date":     "due_date",
+}
+
+// GetInvoices lists the invoices of the projects the caller manages, newest
+// first, without their line items and payments. Filter with ?project_id=,
+// ?status= (comma separated) and ?overdue=true|false.
+func GetInvoices(c *gin.Context) {
+	list, err := parseListQuery(c, "invoices", invoiceSorts, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := database.DB.Where("project_id IN (?)", memberProjectIDs(c, models.ProjectRoleManager))
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if v := c.Query("status"); v != "" {
+		statuses := strings.Split(strings.ToLower(v), ",")
+		for _, s := range statuses {
+			if _, ok := invoiceTransitions[s]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid status %q", s)})
+				return
+			}
+		}
+		query = query.Where("status IN ?", statuses)
+	}
+	switch c.Query("overdue") {
+	case "":
+	case "true":
+		query = query.Where("overdue")
+	case "false":
+		query = query.Where("NOT overdue")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "overdue must be true or false"})
+		return
+	}
+
+	var invoices []models.Invoice
+	if err := list.apply(query).Find(&invoices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invoices"})
+		return
+	}
+	invoices = page(c, list, invoices, func(i models.Invoice) uint { return i.ID })
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+func GetInvoice(c *gin.Context) {
+	invoice, ok := findInvoice(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// CreateInvoice stores a draft invoice computed like GenerateInvoice, or from
+// a completed milestone when milestoneId is given.
+func CreateInvoice(c *gin.Context) {
+	var req CreateInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invoice := models.Invoice{
+		Status:             models.InvoiceStatusDraft,
+		ProjectID:          req.ProjectID,
+		MilestoneID:        req.MilestoneID,
+		IncludeSubprojects: req.IncludeSubprojects && req.MilestoneID == nil,
+		Notes:              req.Notes,
+		PaymentTermsDays:   models.DefaultPaymentTermsDays,
+		UserID:             utils.GetUserID(c),
+	}
+	if req.PaymentTermsDays != nil {
+		invoice.PaymentTermsDays = *req.PaymentTermsDays
+	}
+	if invoice.PaymentTermsDays < 0 || invoice.PaymentTermsDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment terms must be between 0 and 365 days"})
+		return
+	}
+	if req.MilestoneID == nil {
+		var err error
+		if invoice.StartDate, err = time.Parse("2006-01-02", req.StartDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
+			return
+		}
+		if invoice.EndDate, err = time.Parse("2006-01-02", req.EndDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format"})
+			return
+		}
+	}
+
+	if !computeInvoice(c, &invoice) {
+		return
+	}
+
+	if err := database.DB.Create(&invoice).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating invoice"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// UpdateInvoice changes the period or notes of a draft and recomputes its
+// line items from the current time entries.
+func UpdateInvoice(c *gin.Context) {
+	invoice, ok := findInvoice(c)
+	if !ok {
+		return
+	}
+	if invoice.Status != models.InvoiceStatusDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft invoices can be changed"})
+		return
+	}
+
+	var req UpdateInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if invoice.MilestoneID != nil && (req.StartDate != nil || req.EndDate != nil || req.IncludeSubprojects != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The period of a milestone invoice follows the milestone"})
+		return
+	}
+	if req.StartDate != nil {
+		d, err := time.Parse("2006-01-02", *req.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
+			return
+		}
+		invoice.StartDate = d
+	}
+	if req.EndDate != nil {
+		d, err := time.Parse("2006-01-02", *req.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format"})
+			return
+		}
+		invoice.EndDate = d
+	}
+	if req.IncludeSubprojects != nil {
+		invoice.IncludeSubprojects = *req.IncludeSubprojects
+	}
+	if req.Notes != nil {
+		invoice.Notes = *req.Notes
+	}
+	if req.PaymentTermsDays != nil {
+		if *req.PaymentTermsDays < 0 || *req.PaymentTermsDays > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Payment terms must be between 0 and 365 days"})
+			return
+		}
+		invoice.PaymentTermsDays = *req.PaymentTermsDays
+	}
+
+	if !computeInvoice(c, &invoice) {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, invoice.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != models.InvoiceStatusDraft {
+			return errInvoiceNotDraft
+		}
+		return saveInvoice(tx, &invoice)
+	})
+	if errors.Is(err, errInvoiceNotDraft) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft invoices can be changed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating invoice"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// IssueInvoice recomputes a draft one last time and issues it under the
+// next number of the caller's invoice sequence, due after its payment terms.
+// From then on its line items no longer follow the time entries.
+func IssueInvoice(c *gin.Context) {
+	invoice, ok := findInvoice(c)
+	if !ok {
+		return
+	}
+	if invoice.Status != models.InvoiceStatusDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invoice is already issued"})
+		return
+	}
+
+	if !computeInvoice(c, &invoice) {
+		return
+	}
+	if len(invoice.LineItems) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice has nothing to bill"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, invoice.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != models.InvoiceStatusDraft {
+			return errInvoiceNotDraft
+		}
+
+		now := time.Now()
+		number, sequenceID, err := allocateInvoiceNumber(tx, invoice.WorkspaceID, utils.GetUserID(c), now)
+		if err != nil {
+			return err
+		}
+		invoice.Number = number
+		invoice.SequenceID = &sequenceID
+		if err := setInvoiceStatus(&invoice, models.InvoiceStatusIssued); err != nil {
+			return err
+		}
+		dueDate := invoiceDueDate(now, invoice.PaymentTermsDays)
+		invoice.IssuedAt = &now
+		invoice.DueDate = &dueDate
+		return saveInvoice(tx, &invoice)
+	})
+	if errors.Is(err, errInvoiceNotDraft) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invoice is already issued"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error issuing invoice"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+func DeleteInvoice(c *gin.Context) {
+	invoice, ok := findInvoice(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("status = ?", models.InvoiceStatusDraft).Delete(&invoice)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvoiceNotDraft
+		}
+		return tx.Unscoped().Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceLineItem{}).Error
+	})
+	if errors.Is(err, errInvoiceNotDraft) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft invoices can be deleted"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting invoice"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+var errInvoiceNotDraft = errors.New("invoice is not a draft")
+
+// findInvoice loads the invoice named by the :id param, with its line items
+// and payments, from the projects the caller manages.
+func findInvoice(c *gin.Context) (models.Invoice, bool) {
+	var invoice models.Invoice
+	err := database.DB.
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Payments", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") }).
+		Where("id = ? AND project_id IN (?)", c.Param("id"), memberProjectIDs(c, models.ProjectRoleManager)).
+		First(&invoice).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return invoice, false
+	}
+	return invoice, true
+}
+
+// computeInvoice fills the totals and line items of invoice from the time
+// entries of its project and period, or from its milestone, writing the error
+// response when it cannot.
+func computeInvoice(c *gin.Context, invoice *models.Invoice) bool {
+	var response *InvoiceResponse
+	var project models.Project
+
+	if invoice.MilestoneID != nil {
+		milestone, ok := findManagedMilestone(c, strconv.FormatUint(uint64(*invoice.MilestoneID), 10), utils.GetUserID(c))
+		if !ok {
+			return false
+		}
+		if milestone.Status != models.MilestoneStatusCompleted {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Milestone is not completed"})
+			return false
+		}
+
+		var err error
+		if response, err = buildMilestoneInvoice(milestone); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating invoice"})
+			return false
+		}
+		if err := database.DB.First(&project, milestone.ProjectID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating invoice"})
+			return false
+		}
+		invoice.StartDate, _ = time.Parse("2006-01-02", response.StartDate)
+		invoice.EndDate, _ = time.Parse("2006-01-02", response.EndDate)
+	} else {
+		if invoice.EndDate.Before(invoice.StartDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "End date is before start date"})
+			return false
+		}
+
+		var ok bool
+		response, project, ok = buildInvoice(c, InvoiceRequest{
+			ProjectID:          invoice.ProjectID,
+			StartDate:          invoice.StartDate.Format("2006-01-02"),
+			EndDate:            invoice.EndDate.Format("2006-01-02"),
+			IncludeSubprojects: invoice.IncludeSubprojects,
 		})
+		if !ok {
+			return false
+		}
 	}
 
-	// Calculate totals
-	var totalHours float64
-	for _, hours := range entriesByDate {
-		totalHours += hours
+	invoice.ProjectID = project.ID
+	invoice.ProjectName = response.ProjectName
+	invoice.WorkspaceID = project.WorkspaceID
+	invoice.Currency = response.Currency
+	invoice.TotalHours = response.TotalHours
+	invoice.TotalAmount = response.TotalAmount
+	invoice.CustomFields = nil
+	if len(response.CustomFields) > 0 {
+		invoice.CustomFields = make(models.JSONMap)
+		for key, value := range response.CustomFields {
+			invoice.CustomFields[key] = value
+		}
 	}
-	totalAmount := totalHours * project.HourlyRate
+	invoice.LineItems = invoiceLineItems(response, project, invoice.MilestoneID != nil)
+	return true
+}
 
-	response := InvoiceResponse{
-		ProjectName: project.Name,
-		StartDate:   req.StartDate,
-		EndDate:     req.EndDate,
-		TotalHours:  totalHours,
-		HourlyRate:  project.HourlyRate,
-		TotalAmount: totalAmount,
-		Entries:     formattedEntries,
+// invoiceLineItems turns a computed invoice into line items: the fixed amount
+// of a milestone, one line per project when sub-projects are included, and
+// one line per day otherwise.
+func invoiceLineItems(response *InvoiceResponse, project models.Project, milestone bool) []models.InvoiceLineItem {
+	items := []models.InvoiceLineItem{}
+	switch {
+	case milestone:
+		items = append(items, models.InvoiceLineItem{
+			Description: response.Milestone,
+			ProjectID:   &project.ID,
+			Hours:       response.TotalHours,
+			Currency:    response.Currency,
+			Amount:      response.TotalAmount,
+		})
+	case len(response.Projects) > 0:
+		for _, line := range response.Projects {
+			if line.Hours == 0 {
+				continue
+			}
+			projectID := line.ProjectID
+			items = append(items, models.InvoiceLineItem{
+				Description: line.ProjectName,
+				ProjectID:   &projectID,
+				Hours:       line.Hours,
+				HourlyRate:  line.HourlyRate,
+				Currency:    line.Currency,
+				Amount:      line.Amount,
+			})
+		}
+	default:
+		for _, entry := range response.Entries {
+			date, _ := time.Parse("2006-01-02", entry.Date)
+			item := models.InvoiceLineItem{
+				Description: entry.Description,
+				Date:        &date,
+				ProjectID:   &project.ID,
+				Hours:       entry.Hours,
+				HourlyRate:  response.HourlyRate,
+				Currency:    response.Currency,
+				Amount:      entry.Hours * response.HourlyRate,
+			}
+			if len(entry.Fields) > 0 {
+				item.Fields = make(models.JSONMap)
+				for key, value := range entry.Fields {
+					item.Fields[key] = value
+				}
+			}
+			items = append(items, item)
+		}
 	}
 
-	c.JSON(http.StatusOK, response)
+	for i := range items {
+		items[i].Position = i
+	}
+	return items
+}
+
+// saveInvoice writes invoice and replaces its line items.
+func saveInvoice(tx *gorm.DB, invoice *models.Invoice) error {
+	if err := tx.Omit("LineItems", "Payments").Save(invoice).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceLineItem{}).Error; err != nil {
+		return err
+	}
+	if len(invoice.LineItems) == 0 {
+		return nil
+	}
+	for i := range invoice.LineItems {
+		invoice.LineItems[i].ID = 0
+		invoice.LineItems[i].InvoiceID = invoice.ID
+	}
+	return tx.Create(&invoice.LineItems).Error
 }
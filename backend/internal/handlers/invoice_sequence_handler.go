@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/numbering"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	InvoiceSequenceScopeWorkspace = "workspace"
+	InvoiceSequenceScopeUser      = "user"
+)
+
+type InvoiceSequenceRequest struct {
+	Format      string `json:"format"`
+	ResetYearly *bool  `json:"reset_yearly"` // default true
+}
+
+// GetInvoiceSequences lists the workspace sequence and the caller's personal
+// one, each with the number it hands out next. Until the workspace sequence
+// is configured or first used it is shown with the default format.
+func GetInvoiceSequences(c *gin.Context) {
+	workspaceID, userID := utils.GetWorkspaceID(c), utils.GetUserID(c)
+
+	var sequences []models.InvoiceSequence
+	err := database.DB.Where("workspace_id = ? AND user_id IN ?", workspaceID, []uint{0, userID}).
+		Order("user_id").
+		Find(&sequences).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invoice sequences"})
+		return
+	}
+
+	if len(sequences) == 0 || sequences[0].UserID != 0 {
+		sequences = append([]models.InvoiceSequence{defaultInvoiceSequence(workspaceID)}, sequences...)
+	}
+	now := time.Now()
+	for i := range sequences {
+		sequences[i].NextNumber = peekInvoiceNumber(sequences[i], now)
+	}
+
+	c.JSON(http.StatusOK, sequences)
+}
+
+// errSequenceOverlap rejects a format that could hand out a number another
+// sequence of the workspace also hands out.
+var errSequenceOverlap = errors.New("Format can produce the same numbers as another invoice sequence in the workspace")
+
+// SaveInvoiceSequence sets the format of the workspace sequence (admins only)
+// or of the caller's personal sequence, named by :scope. The counter carries
+// on, so changing the format never reissues a number, and the format may not
+// produce numbers another sequence of the workspace can produce.
+func SaveInvoiceSequence(c *gin.Context) {
+	workspaceID := utils.GetWorkspaceID(c)
+
+	var userID uint
+	switch c.Param("scope") {
+	case InvoiceSequenceScopeWorkspace:
+		if !isWorkspaceAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only workspace admins can change the workspace invoice sequence"})
+			return
+		}
+	case InvoiceSequenceScopeUser:
+		userID = utils.GetUserID(c)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be workspace or user"})
+		return
+	}
+
+	var req InvoiceSequenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resetYearly := req.ResetYearly == nil || *req.ResetYearly
+	if err := numbering.Validate(req.Format, resetYearly); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var sequence models.InvoiceSequence
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Format changes in a workspace take turns on its sequence row, so
+		// two of them cannot both pass the overlap check
+		if err := ensureInvoiceSequence(tx, workspaceID, 0); err != nil {
+			return err
+		}
+		var others []models.InvoiceSequence
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("workspace_id = ?", workspaceID).
+			Order("user_id").
+			Find(&others).Error
+		if err != nil {
+			return err
+		}
+		for _, other := range others {
+			if other.UserID != userID && numbering.Overlaps(req.Format, other.Format) {
+				return errSequenceOverlap
+			}
+		}
+
+		if err := ensureInvoiceSequence(tx, workspaceID, userID); err != nil {
+			return err
+		}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+			First(&sequence).Error
+		if err != nil {
+			return err
+		}
+		sequence.Format = req.Format
+		sequence.ResetYearly = resetYearly
+		return tx.Model(&sequence).Updates(map[string]interface{}{
+			"format":       sequence.Format,
+			"reset_yearly": sequence.ResetYearly,
+		}).Error
+	})
+	if errors.Is(err, errSequenceOverlap) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving invoice sequence"})
+		return
+	}
+
+	sequence.NextNumber = peekInvoiceNumber(sequence, time.Now())
+	c.JSON(http.StatusOK, sequence)
+}
+
+// allocateInvoiceNumber takes the next number for an invoice issued at by
+// userID: from their personal sequence if they have one, otherwise from the
+// workspace sequence. It must run in the transaction that issues the
+// invoice; the sequence row stays locked until it commits, and a rollback
+// returns the number, so numbers are neither duplicated nor skipped.
+func allocateInvoiceNumber(tx *gorm.DB, workspaceID, userID uint, at time.Time) (string, uint, error) {
+	var sequence models.InvoiceSequence
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		First(&sequence).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := ensureInvoiceSequence(tx, workspaceID, 0); err != nil {
+			return "", 0, err
+		}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("workspace_id = ? AND user_id = 0", workspaceID).
+			First(&sequence).Error
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	if sequence.ResetYearly && sequence.Year != at.Year() {
+		sequence.LastNumber = 0
+	}
+	sequence.Year = at.Year()
+	sequence.LastNumber++
+
+	err = tx.Model(&sequence).Updates(map[string]interface{}{
+		"year":        sequence.Year,
+		"last_number": sequence.LastNumber,
+	}).Error
+	if err != nil {
+		return "", 0, err
+	}
+
+	return numbering.Format(sequence.Format, at, sequence.LastNumber), sequence.ID, nil
+}
+
+// ensureInvoiceSequence creates the sequence of workspaceID and userID with
+// the default format unless it exists, without racing a concurrent creation.
+func ensureInvoiceSequence(tx *gorm.DB, workspaceID, userID uint) error {
+	sequence := defaultInvoiceSequence(workspaceID)
+	sequence.UserID = userID
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence).Error
+}
+
+func defaultInvoiceSequence(workspaceID uint) models.InvoiceSequence {
+	return models.InvoiceSequence{
+		Format:      models.DefaultInvoiceNumberFormat,
+		ResetYearly: true,
+		WorkspaceID: workspaceID,
+	}
+}
+
+// peekInvoiceNumber is the number sequence would hand out at.
+func peekInvoiceNumber(sequence models.InvoiceSequence, at time.Time) string {
+	next := sequence.LastNumber + 1
+	if sequence.ResetYearly && sequence.Year != at.Year() {
+		next = 1
+	}
+	return numbering.Format(sequence.Format, at, next)
+}
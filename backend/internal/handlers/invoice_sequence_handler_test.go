@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TestAllocateInvoiceNumberConcurrent issues invoices in parallel, rolling
+// some of them back, and checks the issued numbers run 1..n without gaps or
+// duplicates.
+func TestAllocateInvoiceNumberConcurrent(t *testing.T) {
+	user, project := testProject(t)
+	const n = 24
+	errRollback := errors.New("rollback")
+
+	invoices := make([]models.Invoice, n)
+	for i := range invoices {
+		invoices[i] = models.Invoice{
+			Status:      models.InvoiceStatusDraft,
+			ProjectID:   project.ID,
+			ProjectName: project.Name,
+			Currency:    "USD",
+			WorkspaceID: project.WorkspaceID,
+			UserID:      user.ID,
+		}
+		if err := database.DB.Create(&invoices[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := range invoices {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := database.DB.Transaction(func(tx *gorm.DB) error {
+				number, _, err := allocateInvoiceNumber(tx, project.WorkspaceID, user.ID, now)
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&invoices[i]).Updates(map[string]interface{}{"number": number, "status": models.InvoiceStatusIssued}).Error; err != nil {
+					return err
+				}
+				if i%4 == 3 {
+					return errRollback
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errRollback) {
+				t.Errorf("issuing invoice %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var numbers []string
+	err := database.DB.Model(&models.Invoice{}).
+		Where("workspace_id = ? AND number <> ''", project.WorkspaceID).
+		Pluck("number", &numbers).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(numbers)
+	if want := n - n/4; len(numbers) != want {
+		t.Fatalf("issued %d invoices, want %d", len(numbers), want)
+	}
+	for i, number := range numbers {
+		if want := fmt.Sprintf("INV-%d-%04d", now.Year(), i+1); number != want {
+			t.Fatalf("invoice number %d is %s, want %s", i+1, number, want)
+		}
+	}
+}
+
+func TestSaveInvoiceSequenceOverlap(t *testing.T) {
+	user, _ := testProject(t)
+
+	save := func(scope, format string) int {
+		t.Helper()
+		body := fmt.Sprintf(`{"format": %q}`, format)
+		c, w := testContext(user, http.MethodPut, "/invoice-sequences/"+scope, strings.NewReader(body), "scope", scope)
+		SaveInvoiceSequence(c)
+		return w.Code
+	}
+
+	tests := []struct {
+		scope, format string
+		want          int
+	}{
+		{InvoiceSequenceScopeUser, models.DefaultInvoiceNumberFormat, http.StatusConflict},
+		{InvoiceSequenceScopeUser, "INV-{YYYY}-{000}", http.StatusConflict},
+		{InvoiceSequenceScopeUser, "ME-{YYYY}-{000}", http.StatusOK},
+		{InvoiceSequenceScopeUser, "ME-{YYYY}-{00000}", http.StatusOK},
+		{InvoiceSequenceScopeWorkspace, "ME-{YYYY}-{0000}", http.StatusConflict},
+		{InvoiceSequenceScopeWorkspace, "WS-{YYYY}-{0000}", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := save(tt.scope, tt.format); got != tt.want {
+			t.Errorf("saving %s format %s: %d, want %d", tt.scope, tt.format, got, tt.want)
+		}
+	}
+}
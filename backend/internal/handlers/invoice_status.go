@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// invoiceTransitions lists the statuses an invoice may move to from each
+// status. Drafts are issued by IssueInvoice, which also numbers them.
+var invoiceTransitions = map[string][]string{
+	models.InvoiceStatusDraft:         {models.InvoiceStatusIssued},
+	models.InvoiceStatusIssued:        {models.InvoiceStatusSent, models.InvoiceStatusPartiallyPaid, models.InvoiceStatusPaid, models.InvoiceStatusVoid},
+	models.InvoiceStatusSent:          {models.InvoiceStatusPartiallyPaid, models.InvoiceStatusPaid, models.InvoiceStatusVoid},
+	models.InvoiceStatusPartiallyPaid: {models.InvoiceStatusPaid, models.InvoiceStatusVoid},
+	models.InvoiceStatusPaid:          {},
+	models.InvoiceStatusVoid:          {},
+}
+
+// openInvoiceStatuses are the statuses of invoices still awaiting payment.
+var openInvoiceStatuses = []string{models.InvoiceStatusIssued, models.InvoiceStatusSent, models.InvoiceStatusPartiallyPaid}
+
+// invoiceStatusError reports a status change the lifecycle does not allow.
+type invoiceStatusError struct{ from, to string }
+
+func (e invoiceStatusError) Error() string {
+	return fmt.Sprintf("Cannot change a %s invoice to %s", e.from, e.to)
+}
+
+type InvoicePaymentRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Date   string  `json:"date"` // defaults to today
+	Note   string  `json:"note"`
+}
+
+// setInvoiceStatus moves the invoice to status, checking the transition and
+// recording when it was sent, paid or voided. Paid and void invoices are no
+// longer overdue.
+func setInvoiceStatus(invoice *models.Invoice, status string) error {
+	if _, ok := invoiceTransitions[status]; !ok {
+		return fmt.Errorf("status must be one of draft, issued, sent, partially_paid, paid or void")
+	}
+	if invoice.Status == status {
+		return nil
+	}
+
+	allowed := false
+	for _, next := range invoiceTransitions[invoice.Status] {
+		if next == status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return invoiceStatusError{from: invoice.Status, to: status}
+	}
+
+	now := time.Now()
+	switch status {
+	case models.InvoiceStatusSent:
+		invoice.SentAt = &now
+	case models.InvoiceStatusPaid:
+		invoice.PaidAt = &now
+		invoice.Overdue = false
+	case models.InvoiceStatusVoid:
+		invoice.VoidedAt = &now
+		invoice.Overdue = false
+	}
+	invoice.Status = status
+
+	return nil
+}
+
+// saveInvoiceStatus persists the fields set by setInvoiceStatus and by
+// payments.
+func saveInvoiceStatus(tx *gorm.DB, invoice *models.Invoice) error {
+	return tx.Model(invoice).Updates(map[string]interface{}{
+		"status":      invoice.Status,
+		"amount_paid": invoice.AmountPaid,
+		"overdue":     invoice.Overdue,
+		"sent_at":     invoice.SentAt,
+		"paid_at":     invoice.PaidAt,
+		"voided_at":   invoice.VoidedAt,
+	}).Error
+}
+
+// SendInvoice marks an issued invoice as sent to the client.
+func SendInvoice(c *gin.Context) {
+	changeInvoiceStatus(c, models.InvoiceStatusSent)
+}
+
+// VoidInvoice cancels an issued invoice. Its number stays taken, so the
+// sequence keeps no gaps.
+func VoidInvoice(c *gin.Context) {
+	changeInvoiceStatus(c, models.InvoiceStatusVoid)
+}
+
+func changeInvoiceStatus(c *gin.Context, status string) {
+	invoice, ok := findInvoice(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, invoice.ID).Error; err != nil {
+			return err
+		}
+		if err := setInvoiceStatus(&current, status); err != nil {
+			return err
+		}
+		return saveInvoiceStatus(tx, &current)
+	})
+	if !writeInvoiceStatusError(c, err) {
+		return
+	}
+
+	if invoice, ok = findInvoice(c); ok {
+		c.JSON(http.StatusOK, invoice)
+	}
+}
+
+// RecordInvoicePayment adds a payment to an issued invoice, which becomes
+// partially_paid, or paid once the payments cover its total.
+func RecordInvoicePayment(c *gin.Context) {
+	invoice, ok := findInvoice(c)
+	if !ok {
+		return
+	}
+
+	var req InvoicePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment := models.InvoicePayment{
+		InvoiceID: invoice.ID,
+		Amount:    roundCents(req.Amount),
+		Date:      time.Now(),
+		Note:      req.Note,
+		UserID:    utils.GetUserID(c),
+	}
+	if req.Date != "" {
+		d, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+			return
+		}
+		payment.Date = d
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, invoice.ID).Error; err != nil {
+			return err
+		}
+
+		if !containsString(openInvoiceStatuses, current.Status) {
+			return invoiceStatusError{from: current.Status, to: models.InvoiceStatusPartiallyPaid}
+		}
+		if payment.Amount > roundCents(current.TotalAmount-current.AmountPaid) {
+			return errPaymentTooLarge
+		}
+		current.AmountPaid = roundCents(current.AmountPaid + payment.Amount)
+
+		status := models.InvoiceStatusPartiallyPaid
+		if current.AmountPaid >= roundCents(current.TotalAmount) {
+			status = models.InvoiceStatusPaid
+		}
+		if err := setInvoiceStatus(&current, status); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		return saveInvoiceStatus(tx, &current)
+	})
+	if errors.Is(err, errPaymentTooLarge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment exceeds the outstanding amount"})
+		return
+	}
+	if !writeInvoiceStatusError(c, err) {
+		return
+	}
+
+	if invoice, ok = findInvoice(c); ok {
+		c.JSON(http.StatusCreated, invoice)
+	}
+}
+
+var errPaymentTooLarge = errors.New("payment exceeds the outstanding amount")
+
+// writeInvoiceStatusError writes the response for an error of a status
+// change and reports whether there was none.
+func writeInvoiceStatusError(c *gin.Context, err error) bool {
+	var statusErr invoiceStatusError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &statusErr):
+		c.JSON(http.StatusConflict, gin.H{"error": statusErr.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating invoice"})
+	}
+	return false
+}
+
+// FlagOverdueInvoices marks the invoices still awaiting payment after their
+// due date as overdue. It runs from the scheduler.
+func FlagOverdueInvoices() error {
+	return database.DB.Model(&models.Invoice{}).
+		Where("status IN ? AND due_date < CURRENT_DATE AND NOT overdue", openInvoiceStatuses).
+		Update("overdue", true).Error
+}
+
+// invoiceDueDate is the day payment of an invoice issued at falls due.
+func invoiceDueDate(at time.Time, termsDays int) time.Time {
+	return time.Date(at.Year(), at.Month(), at.Day()+termsDays, 0, 0, 0, 0, time.UTC)
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
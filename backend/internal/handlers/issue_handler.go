@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/issues"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type IssueTrackerRequest struct {
+	BaseURL  string `json:"base_url"`
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+type LinkIssueRequest struct {
+	Provider string `json:"provider"`
+	Key      string `json:"key"`
+}
+
+type PushWorklogsResponse struct {
+	Pushed []models.TimeEntry `json:"pushed"`
+	Error  string             `json:"error,omitempty"`
+}
+
+func GetIssueTrackers(c *gin.Context) {
+	var trackers []models.IssueTracker
+	if err := database.DB.Where("workspace_id = ?", utils.GetWorkspaceID(c)).Order("provider").Find(&trackers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching issue trackers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trackers)
+}
+
+// SaveIssueTracker connects the workspace to a GitHub or Jira instance, or
+// updates the connection. An empty token keeps the stored one.
+func SaveIssueTracker(c *gin.Context) {
+	if !isWorkspaceAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only workspace admins can connect issue trackers"})
+		return
+	}
+
+	var req IssueTrackerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	provider := c.Param("provider")
+	if _, err := issues.New(provider, req.BaseURL, req.Username, req.Token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tracker := models.IssueTracker{Provider: provider, WorkspaceID: utils.GetWorkspaceID(c)}
+	database.DB.Where(&tracker).First(&tracker)
+
+	if req.Token == "" && tracker.ID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+	if provider == issues.Jira && req.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Jira needs the account email as username"})
+		return
+	}
+
+	tracker.BaseURL = req.BaseURL
+	tracker.Username = req.Username
+	if req.Token != "" {
+		tracker.Token = req.Token
+	}
+	if err := database.DB.Save(&tracker).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving issue tracker"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tracker)
+}
+
+func DeleteIssueTracker(c *gin.Context) {
+	if !isWorkspaceAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only workspace admins can disconnect issue trackers"})
+		return
+	}
+
+	result := database.DB.Unscoped().
+		Where("provider = ? AND workspace_id = ?", c.Param("provider"), utils.GetWorkspaceID(c)).
+		Delete(&models.IssueTracker{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting issue tracker"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Issue tracker not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LinkTaskIssue links a task to an issue in one of the workspace's trackers
+// and pulls the issue's title and status. An empty key removes the link.
+func LinkTaskIssue(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	var req LinkIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Key == "" {
+		err := database.DB.Model(&task).Updates(map[string]interface{}{
+			"external_provider":  "",
+			"external_key":       "",
+			"external_url":       "",
+			"external_status":    "",
+			"external_synced_at": nil,
+		}).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating task"})
+			return
+		}
+		task.ExternalProvider, task.ExternalKey, task.ExternalURL, task.ExternalStatus, task.ExternalSyncedAt = "", "", "", "", nil
+		c.JSON(http.StatusOK, task)
+		return
+	}
+
+	provider, err := issueProvider(c, req.Provider)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := provider.NormalizeKey(req.Key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task.ExternalProvider = req.Provider
+	pullIssue(c, provider, &task, key)
+}
+
+// SyncTaskIssue pulls the title and status of the task's issue again.
+func SyncTaskIssue(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+	if task.ExternalKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task is not linked to an issue"})
+		return
+	}
+
+	provider, err := issueProvider(c, task.ExternalProvider)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pullIssue(c, provider, &task, task.ExternalKey)
+}
+
+// PushTaskWorklogs sends the finished time entries on the task that the
+// caller can see and that were not sent before to its issue: as worklogs on
+// Jira, as comments on GitHub. Entries pushed before a failure stay pushed.
+func PushTaskWorklogs(c *gin.Context) {
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+	if task.ExternalKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task is not linked to an issue"})
+		return
+	}
+
+	provider, err := issueProvider(c, task.ExternalProvider)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var entries []models.TimeEntry
+	err = visibleTimeEntries(c).
+		Where("task_id = ? AND NOT running AND duration > 0 AND worklog_at IS NULL", task.ID).
+		Order("start_time").
+		Find(&entries).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching time entries"})
+		return
+	}
+
+	response := PushWorklogsResponse{Pushed: []models.TimeEntry{}}
+	for _, entry := range entries {
+		// Claim the entry first so a concurrent push cannot send it twice
+		now := time.Now()
+		claim := database.DB.Model(&models.TimeEntry{}).
+			Where("id = ? AND worklog_at IS NULL", entry.ID).
+			Update("worklog_at", now)
+		if claim.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating time entry"})
+			return
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		worklogID, err := provider.AddWorklog(c.Request.Context(), task.ExternalKey, issues.Worklog{
+			Started:  entry.StartTime,
+			Duration: time.Duration(entry.Duration) * time.Second,
+			Comment:  entry.Notes,
+		})
+		if err != nil {
+			database.DB.Model(&entry).Update("worklog_at", nil)
+			response.Error = issueErrorMessage(err)
+			c.JSON(http.StatusBadGateway, response)
+			return
+		}
+
+		entry.WorklogID, entry.WorklogAt = worklogID, &now
+		if err := database.DB.Model(&entry).Update("worklog_id", worklogID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating time entry"})
+			return
+		}
+		response.Pushed = append(response.Pushed, entry)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// newIssueProvider connects to a tracker. Tests replace it with a fake.
+var newIssueProvider = issues.New
+
+// issueProvider connects to the workspace's tracker for the named provider.
+func issueProvider(c *gin.Context, name string) (issues.Provider, error) {
+	var tracker models.IssueTracker
+	if err := database.DB.Where("provider = ? AND workspace_id = ?", name, utils.GetWorkspaceID(c)).First(&tracker).Error; err != nil {
+		return nil, fmt.Errorf("No %q issue tracker is connected to this workspace", name)
+	}
+	return newIssueProvider(tracker.Provider, tracker.BaseURL, tracker.Username, tracker.Token)
+}
+
+// pullIssue fetches the issue under key, links the task to it and brings the
+// task's title and status in line with it. Statuses only follow the issue
+// where the task's state machine allows it, and a task with open subtasks is
+// not completed.
+func pullIssue(c *gin.Context, provider issues.Provider, task *models.Task, key string) {
+	issue, err := provider.GetIssue(c.Request.Context(), key)
+	if errors.Is(err, issues.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": issueErrorMessage(err)})
+		return
+	}
+
+	status := task.Status
+	switch issue.State {
+	case issues.StateDone:
+		if !hasOpenSubtasks(task.ID) {
+			status = models.TaskStatusCompleted
+		}
+	case issues.StateInProgress:
+		if task.Status != models.TaskStatusOnHold {
+			status = models.TaskStatusInProgress
+		}
+	case issues.StateOpen:
+		if task.Status == models.TaskStatusCompleted {
+			status = models.TaskStatusTodo
+		}
+	}
+	statusChanged := status != task.Status && setTaskStatus(task, status) == nil
+
+	now := time.Now()
+	if issue.Title != "" {
+		task.Title = issue.Title
+	}
+	task.ExternalKey = issue.Key
+	task.ExternalURL = issue.URL
+	task.ExternalStatus = issue.Status
+	task.ExternalSyncedAt = &now
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if statusChanged {
+			if err := lockTaskPositions(tx, task.ProjectID); err != nil {
+				return err
+			}
+		}
+		err := tx.Model(task).Updates(map[string]interface{}{
+			"title":              task.Title,
+			"external_provider":  task.ExternalProvider,
+			"external_key":       task.ExternalKey,
+			"external_url":       task.ExternalURL,
+			"external_status":    task.ExternalStatus,
+			"external_synced_at": task.ExternalSyncedAt,
+		}).Error
+		if err != nil || !statusChanged {
+			return err
+		}
+
+		if err := saveTaskStatus(tx, task, utils.GetUserID(c)); err != nil {
+			return err
+		}
+		if task.Status == models.TaskStatusCompleted {
+			next, err := spawnNextInstance(tx, task.ID)
+			if err != nil {
+				return err
+			}
+			if next != nil {
+				task.NextInstanceID = &next.ID
+			}
+		}
+		return syncParentStatus(tx, task.ParentID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+func issueErrorMessage(err error) string {
+	if errors.Is(err, issues.ErrUnauthorized) {
+		return "The issue tracker rejected the workspace's credentials"
+	}
+	return "Error contacting the issue tracker: " + err.Error()
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/issues"
+	"timetracker/internal/models"
+)
+
+// scriptedProvider is a Fake whose worklogs first run before, which can fail
+// them.
+type scriptedProvider struct {
+	*issues.Fake
+	calls  int
+	before func(call int) error
+}
+
+func (p *scriptedProvider) AddWorklog(ctx context.Context, key string, worklog issues.Worklog) (string, error) {
+	p.calls++
+	if p.before != nil {
+		if err := p.before(p.calls); err != nil {
+			return "", err
+		}
+	}
+	return p.Fake.AddWorklog(ctx, key, worklog)
+}
+
+func TestPushTaskWorklogs(t *testing.T) {
+	user, project := testProject(t)
+
+	task := models.Task{
+		Title:            "Export invoices",
+		Status:           models.TaskStatusInProgress,
+		Priority:         models.TaskPriorityMedium,
+		ProjectID:        project.ID,
+		WorkspaceID:      project.WorkspaceID,
+		UserID:           user.ID,
+		ExternalProvider: issues.Jira,
+		ExternalKey:      "PROJ-1",
+	}
+	if err := database.DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	tracker := models.IssueTracker{Provider: issues.Jira, BaseURL: "https://example.atlassian.net", WorkspaceID: project.WorkspaceID}
+	if err := database.DB.Create(&tracker).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	pushedAt := start.Add(time.Hour)
+	entries := []models.TimeEntry{
+		{StartTime: start, Duration: 3600, Notes: "first"},
+		{StartTime: start.AddDate(0, 0, 1), Duration: 1800, Notes: "second"},
+		{StartTime: start.AddDate(0, 0, 2), Running: true},
+		{StartTime: start.AddDate(0, 0, 3), Duration: 600, WorklogID: "old", WorklogAt: &pushedAt},
+		{StartTime: start.AddDate(0, 0, 4), Duration: 900, Notes: "third"},
+	}
+	for i := range entries {
+		entries[i].ProjectID, entries[i].TaskID = project.ID, task.ID
+		entries[i].WorkspaceID, entries[i].UserID = project.WorkspaceID, user.ID
+		entries[i].EndTime = entries[i].StartTime.Add(time.Duration(entries[i].Duration) * time.Second)
+	}
+	if err := database.DB.Create(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &scriptedProvider{Fake: issues.NewFake()}
+	provider.Set(issues.Issue{Key: "PROJ-1", Title: task.Title})
+	previous := newIssueProvider
+	newIssueProvider = func(kind, baseURL, username, token string) (issues.Provider, error) { return provider, nil }
+	t.Cleanup(func() { newIssueProvider = previous })
+
+	push := func(wantStatus int, wantPushed ...int) PushWorklogsResponse {
+		t.Helper()
+		c, w := testContext(user, http.MethodPost, "/tasks/worklogs", nil, "id", fmt.Sprint(task.ID))
+		PushTaskWorklogs(c)
+		if w.Code != wantStatus {
+			t.Fatalf("status = %d, want %d: %s", w.Code, wantStatus, w.Body)
+		}
+		var response PushWorklogsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Pushed) != len(wantPushed) {
+			t.Fatalf("pushed %d entries, want %d", len(response.Pushed), len(wantPushed))
+		}
+		for i, e := range response.Pushed {
+			if e.ID != entries[wantPushed[i]].ID || e.WorklogID == "" || e.WorklogAt == nil {
+				t.Errorf("pushed[%d] = entry %d with worklog %q", i, e.ID, e.WorklogID)
+			}
+		}
+		return response
+	}
+	pushed := func(i int) (string, bool) {
+		t.Helper()
+		var entry models.TimeEntry
+		if err := database.DB.First(&entry, entries[i].ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return entry.WorklogID, entry.WorklogAt != nil
+	}
+
+	// The tracker fails the second worklog: the first stays pushed and the
+	// claim on the second is released
+	provider.before = func(call int) error {
+		if call == 2 {
+			return errors.New("tracker is down")
+		}
+		return nil
+	}
+	if response := push(http.StatusBadGateway, 0); response.Error == "" {
+		t.Error("no error reported")
+	}
+	if id, ok := pushed(0); id == "" || !ok {
+		t.Errorf("first entry: worklog %q, pushed %v", id, ok)
+	}
+	for _, i := range []int{1, 4} {
+		if id, ok := pushed(i); id != "" || ok {
+			t.Errorf("entry %d still claimed: worklog %q, pushed %v", i, id, ok)
+		}
+	}
+
+	// A concurrent push claims the last entry while this one sends the
+	// second, so it is skipped
+	provider.before = func(call int) error {
+		if call == 1 {
+			return database.DB.Model(&models.TimeEntry{}).Where("id = ?", entries[4].ID).Update("worklog_at", time.Now()).Error
+		}
+		return nil
+	}
+	push(http.StatusOK, 1)
+
+	provider.before = nil
+	push(http.StatusOK)
+
+	if got := provider.Worklogs("PROJ-1"); len(got) != 2 || got[0].Comment != "first" || got[1].Comment != "second" || got[1].Duration != 30*time.Minute {
+		t.Errorf("worklogs = %+v", got)
+	}
+	if id, ok := pushed(3); id != "old" || !ok {
+		t.Errorf("entry pushed before: worklog %q, pushed %v", id, ok)
+	}
+	if _, ok := pushed(2); ok {
+		t.Error("running entry pushed")
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// listSorts maps the ?sort= values a list endpoint accepts to SQL expressions
+// over its table.
+type listSorts map[string]string
+
+// listQuery holds the parameters every list endpoint shares: ?sort= and
+// ?order=asc|desc pick the order, with ties broken by id and NULLs last, and
+// ?limit= (at most 200) or ?cursor= ask for a page. Without either the whole
+// list is returned; with them the response carries the cursor of the next
+// page, if any, in the X-Next-Cursor header.
+type listQuery struct {
+	table string
+	sort  string
+	expr  string
+	desc  bool
+	limit int
+	after uint // id of the last row of the previous page
+}
+
+func parseListQuery(c *gin.Context, table string, sorts listSorts, defaultSort, defaultOrder string) (listQuery, error) {
+	q := listQuery{table: table, sort: c.DefaultQuery("sort", defaultSort)}
+
+	q.expr = sorts[q.sort]
+	if q.expr == "" {
+		names := make([]string, 0, len(sorts))
+		for name := range sorts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return q, fmt.Errorf("sort must be one of %s", strings.Join(names, ", "))
+	}
+
+	switch order := strings.ToLower(c.DefaultQuery("order", defaultOrder)); order {
+	case "asc":
+	case "desc":
+		q.desc = true
+	default:
+		return q, errors.New("order must be asc or desc")
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		q.limit = limit
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		after, err := q.decodeCursor(v)
+		if err != nil {
+			return q, err
+		}
+		q.after = after
+		if q.limit == 0 {
+			q.limit = defaultPageSize
+		}
+	}
+
+	return q, nil
+}
+
+// apply orders query and restricts it to the requested page. One row more
+// than the page size is fetched to tell whether another page follows.
+func (q listQuery) apply(query *gorm.DB) *gorm.DB {
+	dir, cmp := "ASC", ">"
+	if q.desc {
+		dir, cmp = "DESC", "<"
+	}
+	id := q.table + ".id"
+
+	if q.after != 0 {
+		// Rows after the cursor row in (expr, id) order, NULLs last
+		last := "(SELECT " + q.expr + " FROM " + q.table + " WHERE id = @cursor)"
+		query = query.Where(
+			"("+last+" IS NOT NULL AND ("+q.expr+" IS NULL OR ("+q.expr+", "+id+") "+cmp+" ("+last+", @cursor)))"+
+				" OR ("+last+" IS NULL AND "+q.expr+" IS NULL AND "+id+" "+cmp+" @cursor)",
+			sql.Named("cursor", q.after))
+	}
+
+	query = query.Order(q.expr + " " + dir + " NULLS LAST").Order(id + " " + dir)
+	if q.limit > 0 {
+		query = query.Limit(q.limit + 1)
+	}
+	return query
+}
+
+// page drops the extra row fetched by apply and, when there was one, sets
+// the X-Next-Cursor header.
+func page[T any](c *gin.Context, q listQuery, rows []T, id func(T) uint) []T {
+	if q.limit == 0 || len(rows) <= q.limit {
+		return rows
+	}
+	rows = rows[:q.limit]
+	c.Header("X-Next-Cursor", q.encodeCursor(id(rows[len(rows)-1])))
+	return rows
+}
+
+// Cursors name the sort they were made for, so a cursor is not reused with a
+// different order.
+func (q listQuery) encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(q.sortKey() + ":" + strconv.FormatUint(uint64(id), 10)))
+}
+
+func (q listQuery) decodeCursor(cursor string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("Invalid cursor")
+	}
+	key, idText, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, errors.New("Invalid cursor")
+	}
+	if key != q.sortKey() {
+		return 0, errors.New("Cursor does not match the sort and order")
+	}
+	id, err := strconv.ParseUint(idText, 10, 64)
+	if err != nil {
+		return 0, errors.New("Invalid cursor")
+	}
+	return uint(id), nil
+}
+
+func (q listQuery) sortKey() string {
+	if q.desc {
+		return q.sort + ".desc"
+	}
+	return q.sort + ".asc"
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvitationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type MemberRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type ProjectMemberRequest struct {
+	Role     string   `json:"role"`
+	CostRate *float64 `json:"cost_rate"`
+}
+
+var invitationSorts = listSorts{"email": "LOWER(email)", "created_at": "created_at"}
+
+func GetProjectMembers(c *gin.Context) {
+	projectID := c.Param("id")
+
+	if !hasProjectRole(c, projectID, models.ProjectRoleViewer) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	list, err := parseListQuery(c, "project_members", workspaceMemberSorts, "created_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var members []models.ProjectMember
+	if err := list.apply(database.DB.Preload("User").Where("project_id = ?", projectID)).Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching members"})
+		return
+	}
+	members = page(c, list, members, func(m models.ProjectMember) uint { return m.ID })
+
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateProjectMember changes a member's role or cost rate. Only owners may
+// grant or revoke the owner and manager roles.
+func UpdateProjectMember(c *gin.Context) {
+	projectID := c.Param("id")
+	memberID := c.Param("userId")
+
+	var req ProjectMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role != "" && projectRoleRank[req.Role] == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	if req.CostRate != nil && *req.CostRate < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cost rate cannot be negative"})
+		return
+	}
+
+	var member models.ProjectMember
+	if err := database.DB.Where("project_id = ? AND user_id = ?", projectID, memberID).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if !hasProjectRole(c, member.ProjectID, models.ProjectRoleManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to change this member"})
+		return
+	}
+
+	if req.Role != "" && req.Role != member.Role {
+		if !canAssignRole(c, member.ProjectID, member.Role) || !canAssignRole(c, member.ProjectID, req.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to change this member's role"})
+			return
+		}
+
+		if member.Role == models.ProjectRoleOwner && countOwners(member.ProjectID) <= 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A project needs at least one owner"})
+			return
+		}
+
+		member.Role = req.Role
+	}
+	if req.CostRate != nil {
+		member.CostRate = req.CostRate
+	}
+
+	if err := database.DB.Save(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveProjectMember removes a member from a project. Members may always
+// remove themselves.
+func RemoveProjectMember(c *gin.Context) {
+	projectID := c.Param("id")
+	memberID := c.Param("userId")
+	userID := utils.GetUserID(c)
+
+	var member models.ProjectMember
+	if err := database.DB.Where("project_id = ? AND user_id = ?", projectID, memberID).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if member.UserID != userID && !canAssignRole(c, member.ProjectID, member.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to remove this member"})
+		return
+	}
+
+	if member.Role == models.ProjectRoleOwner && countOwners(member.ProjectID) <= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A project needs at least one owner"})
+		return
+	}
+
+	if err := database.DB.Unscoped().Delete(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error removing member"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func GetProjectInvitations(c *gin.Context) {
+	projectID := c.Param("id")
+
+	if !hasProjectRole(c, projectID, models.ProjectRoleManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to view invitations"})
+		return
+	}
+
+	list, err := parseListQuery(c, "project_invitations", invitationSorts, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invitations []models.ProjectInvitation
+	if err := list.apply(database.DB.Where("project_id = ? AND accepted_at IS NULL", projectID)).Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invitations"})
+		return
+	}
+	invitations = page(c, list, invitations, func(i models.ProjectInvitation) uint { return i.ID })
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func CreateProjectInvitation(c *gin.Context) {
+	projectID := c.Param("id")
+	userID := utils.GetUserID(c)
+
+	var req InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if projectRoleRank[req.Role] == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	var project models.Project
+	if err := database.DB.Where("id = ? AND id IN (?)", projectID, memberProjectIDs(c, models.ProjectRoleViewer)).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !canAssignRole(c, project.ID, req.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to invite with this role"})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	var existing int64
+	database.DB.Model(&models.ProjectMember{}).
+		Joins("JOIN users ON users.id = project_members.user_id").
+		Where("project_members.project_id = ? AND LOWER(users.email) = ?", project.ID, email).
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+		return
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating invitation"})
+		return
+	}
+
+	invitation := models.ProjectInvitation{
+		ProjectID:   project.ID,
+		Email:       email,
+		Role:        req.Role,
+		Token:       token,
+		InvitedByID: userID,
+	}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func DeleteProjectInvitation(c *gin.Context) {
+	projectID := c.Param("id")
+	invitationID := c.Param("invitationId")
+
+	if !hasProjectRole(c, projectID, models.ProjectRoleManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to revoke invitations"})
+		return
+	}
+
+	result := database.DB.Where("id = ? AND project_id = ? AND accepted_at IS NULL", invitationID, projectID).Delete(&models.ProjectInvitation{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting invitation"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetMyInvitations lists pending invitations sent to the caller's email.
+func GetMyInvitations(c *gin.Context) {
+	userID := utils.GetUserID(c)
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var invitations []models.ProjectInvitation
+	err := database.DB.Preload("Project").
+		Where("LOWER(email) = ? AND accepted_at IS NULL", strings.ToLower(user.Email)).
+		Find(&invitations).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func AcceptInvitation(c *gin.Context) {
+	token := c.Param("token")
+	userID := utils.GetUserID(c)
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var invitation models.ProjectInvitation
+	err := database.DB.Preload("Project").Where("token = ? AND accepted_at IS NULL", token).First(&invitation).Error
+	if err != nil || !strings.EqualFold(invitation.Email, user.Email) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	var member models.ProjectMember
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&invitation).Update("accepted_at", &now).Error; err != nil {
+			return err
+		}
+		// Project members need to see the project's workspace
+		if err := ensureWorkspaceMember(tx, invitation.Project.WorkspaceID, userID); err != nil {
+			return err
+		}
+
+		// Invitations sent to existing members can raise their role but
+		// never lower it
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("project_id = ? AND user_id = ?", invitation.ProjectID, userID).
+			First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			member = models.ProjectMember{
+				ProjectID: invitation.ProjectID,
+				UserID:    userID,
+				Role:      invitation.Role,
+			}
+			return tx.Create(&member).Error
+		}
+		if err != nil {
+			return err
+		}
+		if projectRoleRank[invitation.Role] <= projectRoleRank[member.Role] {
+			return nil
+		}
+		member.Role = invitation.Role
+		return tx.Model(&member).Update("role", member.Role).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error accepting invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// canAssignRole reports whether the caller may grant or revoke role on the
+// project: owners manage every role, managers only members and viewers.
+func canAssignRole(c *gin.Context, projectID uint, role string) bool {
+	switch projectRole(c, projectID) {
+	case models.ProjectRoleOwner:
+		return true
+	case models.ProjectRoleManager:
+		return role == models.ProjectRoleMember || role == models.ProjectRoleViewer
+	default:
+		return false
+	}
+}
+
+func countOwners(projectID uint) int64 {
+	var count int64
+	database.DB.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND role = ?", projectID, models.ProjectRoleOwner).
+		Count(&count)
+	return count
+}
+
+func generateInvitationToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
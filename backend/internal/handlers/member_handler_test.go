@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+)
+
+func TestAcceptInvitation(t *testing.T) {
+	owner, project := testProject(t)
+	user, _ := testProject(t)
+
+	accept := func(role string) models.ProjectMember {
+		t.Helper()
+		invitation := models.ProjectInvitation{
+			ProjectID:   project.ID,
+			Email:       user.Email,
+			Role:        role,
+			Token:       fmt.Sprintf("%s-%d-%s", t.Name(), project.ID, role),
+			InvitedByID: owner.ID,
+		}
+		if err := database.DB.Create(&invitation).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		c, w := testContext(user, http.MethodPost, "/invitations/"+invitation.Token+"/accept", nil, "token", invitation.Token)
+		AcceptInvitation(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("AcceptInvitation(%s): %d %s", role, w.Code, w.Body)
+		}
+
+		if err := database.DB.First(&invitation, invitation.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if invitation.AcceptedAt == nil {
+			t.Errorf("invitation as %s was not marked accepted", role)
+		}
+
+		var members []models.ProjectMember
+		if err := database.DB.Where("project_id = ? AND user_id = ?", project.ID, user.ID).Find(&members).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(members) != 1 {
+			t.Fatalf("user has %d memberships, want 1", len(members))
+		}
+		return members[0]
+	}
+
+	tests := []struct {
+		invitedAs string
+		want      string
+	}{
+		{models.ProjectRoleMember, models.ProjectRoleMember},
+		{models.ProjectRoleViewer, models.ProjectRoleMember}, // not lowered
+		{models.ProjectRoleManager, models.ProjectRoleManager},
+	}
+	for _, tt := range tests {
+		if member := accept(tt.invitedAs); member.Role != tt.want {
+			t.Errorf("after accepting as %s role = %s, want %s", tt.invitedAs, member.Role, tt.want)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var milestoneStatuses = map[string]bool{
+	models.MilestoneStatusPending:    true,
+	models.MilestoneStatusInProgress: true,
+	models.MilestoneStatusCompleted:  true,
+}
+
+var milestoneSorts = listSorts{"due_date": "due_date", "name": "LOWER(name)", "created_at": "created_at"}
+
+func GetMilestones(c *gin.Context) {
+	list, err := parseListQuery(c, "milestones", milestoneSorts, "due_date", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projectID := c.Query("project_id")
+
+	query := database.DB.Where("project_id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer))
+	if projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var milestones []models.Milestone
+	if err := list.apply(query).Find(&milestones).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching milestones"})
+		return
+	}
+	milestones = page(c, list, milestones, func(m models.Milestone) uint { return m.ID })
+
+	if err := loadMilestoneHours(milestones); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching milestone hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, milestones)
+}
+
+func GetMilestone(c *gin.Context) {
+	milestoneID := c.Param("id")
+
+	var milestone models.Milestone
+	if err := database.DB.Preload("Tasks").Where("id = ? AND project_id IN (?)", milestoneID, memberProjectIDs(c, models.ProjectRoleViewer)).First(&milestone).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Milestone not found"})
+		return
+	}
+
+	milestones := []models.Milestone{milestone}
+	if err := loadMilestoneHours(milestones); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching milestone hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, milestones[0])
+}
+
+// MilestoneRequest holds the fields clients may set on a milestone. Tasks
+// join a milestone through their milestone_id, and CompletedAt follows the
+// status.
+type MilestoneRequest struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	DueDate     *time.Time `json:"due_date"`
+	Amount      float64    `json:"amount"`
+	Status      string     `json:"status"`
+	ProjectID   uint       `json:"project_id"`
+}
+
+func CreateMilestone(c *gin.Context) {
+	var req MilestoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	milestone := models.Milestone{
+		Name:        req.Name,
+		Description: req.Description,
+		DueDate:     req.DueDate,
+		Amount:      req.Amount,
+		Status:      req.Status,
+		ProjectID:   req.ProjectID,
+	}
+
+	userID := utils.GetUserID(c)
+
+	if !hasProjectRole(c, milestone.ProjectID, models.ProjectRoleManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to add milestones to this project"})
+		return
+	}
+
+	if milestone.Status == "" {
+		milestone.Status = models.MilestoneStatusPending
+	}
+	if !milestoneStatuses[milestone.Status] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid milestone status"})
+		return
+	}
+	if milestone.Status == models.MilestoneStatusCompleted {
+		now := time.Now()
+		milestone.CompletedAt = &now
+	}
+
+	milestone.UserID = userID
+
+	if err := database.DB.Create(&milestone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating milestone"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, milestone)
+}
+
+func UpdateMilestone(c *gin.Context) {
+	milestoneID := c.Param("id")
+	userID := utils.GetUserID(c)
+
+	milestone, ok := findManagedMilestone(c, milestoneID, userID)
+	if !ok {
+		return
+	}
+
+	var req MilestoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A milestone cannot be moved to another project.
+	updates := models.Milestone{
+		Name:        req.Name,
+		Description: req.Description,
+		DueDate:     req.DueDate,
+		Amount:      req.Amount,
+		Status:      req.Status,
+	}
+
+	if updates.Status != "" && !milestoneStatuses[updates.Status] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid milestone status"})
+		return
+	}
+	if updates.Status == models.MilestoneStatusCompleted && milestone.CompletedAt == nil {
+		now := time.Now()
+		updates.CompletedAt = &now
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&milestone).Updates(updates).Error; err != nil {
+			return err
+		}
+		// A reopened milestone is completed again on its next completion
+		if updates.Status != "" && updates.Status != models.MilestoneStatusCompleted && milestone.CompletedAt != nil {
+			milestone.CompletedAt = nil
+			return tx.Model(&milestone).Updates(map[string]any{"completed_at": nil}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating milestone"})
+		return
+	}
+
+	c.JSON(http.StatusOK, milestone)
+}
+
+func DeleteMilestone(c *gin.Context) {
+	milestoneID := c.Param("id")
+	userID := utils.GetUserID(c)
+
+	milestone, ok := findManagedMilestone(c, milestoneID, userID)
+	if !ok {
+		return
+	}
+
+	// Unlink tasks so they stay with the project.
+	if err := database.DB.Model(&models.Task{}).Where("milestone_id = ?", milestone.ID).Update("milestone_id", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting milestone"})
+		return
+	}
+
+	if err := database.DB.Delete(&milestone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting milestone"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CompleteMilestone marks a milestone as completed. Pass ?invoice=true to get
+// the milestone invoice back in the same call.
+func CompleteMilestone(c *gin.Context) {
+	milestoneID := c.Param("id")
+	userID := utils.GetUserID(c)
+
+	milestone, ok := findManagedMilestone(c, milestoneID, userID)
+	if !ok {
+		return
+	}
+
+	if milestone.Status != models.MilestoneStatusCompleted {
+		now := time.Now()
+		milestone.Status = models.MilestoneStatusCompleted
+		milestone.CompletedAt = &now
+		if err := database.DB.Save(&milestone).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error completing milestone"})
+			return
+		}
+	}
+
+	if c.Query("invoice") != "true" {
+		c.JSON(http.StatusOK, milestone)
+		return
+	}
+
+	invoice, err := buildMilestoneInvoice(milestone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating invoice"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"milestone": milestone, "invoice": invoice})
+}
+
+func GenerateMilestoneInvoice(c *gin.Context) {
+	milestoneID := c.Param("id")
+	userID := utils.GetUserID(c)
+
+	milestone, ok := findManagedMilestone(c, milestoneID, userID)
+	if !ok {
+		return
+	}
+
+	if milestone.Status != models.MilestoneStatusCompleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Milestone is not completed"})
+		return
+	}
+
+	invoice, err := buildMilestoneInvoice(milestone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating invoice"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// buildMilestoneInvoice bills the fixed milestone amount and lists the time
+// tracked against the milestone's tasks for reference.
+func buildMilestoneInvoice(milestone models.Milestone) (*InvoiceResponse, error) {
+	var project models.Project
+	if err := database.DB.First(&project, milestone.ProjectID).Error; err != nil {
+		return nil, err
+	}
+
+	var entries []models.TimeEntry
+	err := database.DB.
+		Where("task_id IN (?)", database.DB.Model(&models.Task{}).Select("id").Where("milestone_id = ?", milestone.ID)).
+		Order("start_time").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	formattedEntries, totalHours := groupEntriesByDate(entries)
+
+	invoice := &InvoiceResponse{
+		ProjectName: project.Name,
+		Milestone:   milestone.Name,
+		TotalHours:  totalHours,
+		HourlyRate:  project.HourlyRate,
+		Currency:    project.Currency,
+		TotalAmount: milestone.Amount,
+		Entries:     formattedEntries,
+	}
+	if len(formattedEntries) > 0 {
+		invoice.StartDate = formattedEntries[0].Date
+		invoice.EndDate = formattedEntries[len(formattedEntries)-1].Date
+	} else if milestone.CompletedAt != nil {
+		invoice.StartDate = milestone.CompletedAt.Format("2006-01-02")
+		invoice.EndDate = invoice.StartDate
+	}
+
+	if err := addInvoiceCustomFields(invoice, project); err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// loadMilestoneHours fills TrackedHours with the time logged against each
+// milestone's tasks.
+func loadMilestoneHours(milestones []models.Milestone) error {
+	if len(milestones) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(milestones))
+	for i, m := range milestones {
+		ids[i] = m.ID
+	}
+
+	var rows []struct {
+		MilestoneID uint
+		Seconds     int64
+	}
+	err := database.DB.Model(&models.TimeEntry{}).
+		Select("tasks.milestone_id, COALESCE(SUM(time_entries.duration), 0) AS seconds").
+		Joins("JOIN tasks ON tasks.id = time_entries.task_id AND tasks.deleted_at IS NULL").
+		Where("tasks.milestone_id IN ?", ids).
+		Group("tasks.milestone_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	hours := make(map[uint]float64)
+	for _, row := range rows {
+		hours[row.MilestoneID] = float64(row.Seconds) / 3600
+	}
+	for i := range milestones {
+		milestones[i].TrackedHours = hours[milestones[i].ID]
+	}
+
+	return nil
+}
+
+// findManagedMilestone loads a milestone the user can see and checks they may
+// change it, writing the error response when they cannot.
+func findManagedMilestone(c *gin.Context, milestoneID string, userID uint) (models.Milestone, bool) {
+	var milestone models.Milestone
+	if err := database.DB.Where("id = ? AND project_id IN (?)", milestoneID, memberProjectIDs(c, models.ProjectRoleViewer)).First(&milestone).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Milestone not found"})
+		return milestone, false
+	}
+
+	if !hasProjectRole(c, milestone.ProjectID, models.ProjectRoleManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage this milestone"})
+		return milestone, false
+	}
+
+	return milestone, true
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+)
+
+func TestUpdateMilestoneStatus(t *testing.T) {
+	user, project := testProject(t)
+
+	milestone := models.Milestone{Name: "Beta", ProjectID: project.ID, UserID: user.ID}
+	if err := database.DB.Create(&milestone).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(status string) models.Milestone {
+		t.Helper()
+		body := fmt.Sprintf(`{"status": %q}`, status)
+		c, w := testContext(user, http.MethodPut, fmt.Sprintf("/milestones/%d", milestone.ID), strings.NewReader(body), "id", fmt.Sprint(milestone.ID))
+		UpdateMilestone(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("UpdateMilestone(%s): %d %s", status, w.Code, w.Body)
+		}
+		var m models.Milestone
+		if err := database.DB.First(&m, milestone.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	if m := update(models.MilestoneStatusCompleted); m.CompletedAt == nil {
+		t.Error("completed milestone has no completion time")
+	}
+	if m := update(models.MilestoneStatusInProgress); m.CompletedAt != nil {
+		t.Errorf("reopened milestone still completed at %v", m.CompletedAt)
+	}
+	if m := update(models.MilestoneStatusCompleted); m.CompletedAt == nil {
+		t.Error("milestone completed again has no completion time")
+	}
+}
+
+func TestCreateMilestoneFields(t *testing.T) {
+	user, project := testProject(t)
+	other, otherProject := testProject(t)
+
+	task := models.Task{Title: "Elsewhere", Status: models.TaskStatusTodo, ProjectID: otherProject.ID, WorkspaceID: otherProject.WorkspaceID, UserID: other.ID}
+	if err := database.DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := fmt.Sprintf(`{"name": "Launch", "project_id": %d, "completed_at": "2024-01-01T00:00:00Z",
+		"tasks": [{"ID": %d}, {"title": "Injected", "project_id": %d}]}`, project.ID, task.ID, otherProject.ID)
+	c, w := testContext(user, http.MethodPost, "/milestones", strings.NewReader(body))
+	CreateMilestone(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateMilestone: %d %s", w.Code, w.Body)
+	}
+
+	var milestone models.Milestone
+	if err := database.DB.Where("project_id = ?", project.ID).First(&milestone).Error; err != nil {
+		t.Fatal(err)
+	}
+	if milestone.CompletedAt != nil {
+		t.Errorf("pending milestone completed at %v", milestone.CompletedAt)
+	}
+	var linked int64
+	database.DB.Model(&models.Task{}).Where("milestone_id = ?", milestone.ID).Count(&linked)
+	if linked != 0 {
+		t.Errorf("%d tasks linked through the request body", linked)
+	}
+	var injected int64
+	database.DB.Model(&models.Task{}).Where("project_id = ? AND title = ?", otherProject.ID, "Injected").Count(&injected)
+	if injected != 0 {
+		t.Error("task created in another project through the request body")
+	}
+}
@@ -2,32 +2,53 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"timetracker/internal/database"
+	"timetracker/internal/exchange"
 	"timetracker/internal/models"
 	"timetracker/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+var projectSorts = listSorts{"name": "LOWER(name)", "created_at": "created_at", "updated_at": "updated_at"}
+
 func GetProjects(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	list, err := parseListQuery(c, "projects", projectSorts, "created_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var projects []models.Project
 
-	if err := database.DB.Where("user_id = ?", userID).Find(&projects).Error; err != nil {
+	query := database.DB.Where("id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer))
+	switch parentID := c.Query("parent_id"); parentID {
+	case "":
+	case "none":
+		query = query.Where("parent_id IS NULL")
+	default:
+		query = query.Where("parent_id = ?", parentID)
+	}
+	query = applyCustomFieldFilters(c, query, "projects")
+
+	if err := list.apply(query).Find(&projects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching projects"})
 		return
 	}
+	projects = page(c, list, projects, func(p models.Project) uint { return p.ID })
 
 	c.JSON(http.StatusOK, projects)
 }
 
 func GetProject(c *gin.Context) {
 	projectID := c.Param("id")
-	userID := utils.GetUserID(c)
 	var project models.Project
 
-	if err := database.DB.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error; err != nil {
+	if err := database.DB.Where("id = ? AND id IN (?)", projectID, memberProjectIDs(c, models.ProjectRoleViewer)).First(&project).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
@@ -43,8 +64,32 @@ func CreateProject(c *gin.Context) {
 	}
 
 	project.UserID = utils.GetUserID(c)
+	project.WorkspaceID = utils.GetWorkspaceID(c)
+
+	if project.ClientID != nil && !clientInWorkspace(*project.ClientID, project.WorkspaceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Client not found"})
+		return
+	}
+
+	if err := validateProjectParent(c, &project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := setProjectCurrency(&project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var err error
+	project.CustomFields, err = validateCustomFields(c, models.CustomFieldEntityProject, project.CustomFields, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := database.DB.Create(&project).Error; err != nil {
+	project.TimeEntries, project.Tasks, project.Milestones = nil, nil, nil
+	if err := database.DB.Omit("TimeEntries", "Tasks", "Milestones").Create(&project).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating project"})
 		return
 	}
@@ -52,18 +97,56 @@ func CreateProject(c *gin.Context) {
 	c.JSON(http.StatusCreated, project)
 }
 
-// Continue with UpdateProject and DeleteProject...
-
 func UpdateProject(c *gin.Context) {
+	projectID := c.Param("id")
+
 	var project models.Project
+	if err := database.DB.Where("id = ? AND id IN (?)", projectID, memberProjectIDs(c, models.ProjectRoleViewer)).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	if !hasProjectRole(c, project.ID, models.ProjectRoleManager) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to update this project"})
+		return
+	}
+
+	existing := project
 	if err := c.ShouldBindJSON(&project); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	project.UserID = utils.GetUserID(c)
+	project.ID = existing.ID
+	project.CreatedAt = existing.CreatedAt
+	project.UserID = existing.UserID
+	project.WorkspaceID = existing.WorkspaceID
+
+	if project.ClientID != nil && !clientInWorkspace(*project.ClientID, project.WorkspaceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Client not found"})
+		return
+	}
+
+	if !sameParent(project.ParentID, existing.ParentID) {
+		if err := validateProjectParent(c, &project); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := setProjectCurrency(&project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := database.DB.Save(&project).Error; err != nil {
+	var err error
+	project.CustomFields, err = validateCustomFields(c, models.CustomFieldEntityProject, project.CustomFields, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Omit("TimeEntries", "Tasks", "Milestones").Save(&project).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating project"})
 		return
 	}
@@ -71,14 +154,309 @@ func UpdateProject(c *gin.Context) {
 	c.JSON(http.StatusOK, project)
 }
 
+// DeleteProject deletes a project. Its sub-projects move up to the deleted
+// project's parent.
 func DeleteProject(c *gin.Context) {
 	projectID := c.Param("id")
-	userID := utils.GetUserID(c)
 
-	if err := database.DB.Where("id = ? AND user_id = ?", projectID, userID).Delete(&models.Project{}).Error; err != nil {
+	if !hasProjectRole(c, projectID, models.ProjectRoleOwner) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var project models.Project
+	if err := database.DB.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Project{}).Where("parent_id = ?", project.ID).Update("parent_id", project.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&project).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting project"})
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+type DuplicateProjectRequest struct {
+	Name               string `json:"name"`
+	IncludeTimeEntries bool   `json:"include_time_entries"`
+}
+
+// DuplicateProject copies a project together with its milestones, tasks and
+// their dependencies. Stopped time entries are only copied when
+// include_time_entries is set. The body is optional.
+func DuplicateProject(c *gin.Context) {
+	projectID := c.Param("id")
+	userID := utils.GetUserID(c)
+
+	var req DuplicateProjectRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var source models.Project
+	err := database.DB.Preload("Milestones").Preload("Tasks.Checklist").Preload("Tasks.Tags").
+		Where("id = ? AND id IN (?)", projectID, memberProjectIDs(c, models.ProjectRoleManager)).
+		First(&source).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	project := models.Project{
+		Name:         req.Name,
+		Description:  source.Description,
+		HourlyRate:   source.HourlyRate,
+		Currency:     source.Currency,
+		BudgetHours:  source.BudgetHours,
+		BudgetAmount: source.BudgetAmount,
+		ClientID:     source.ClientID,
+		ParentID:     source.ParentID,
+		CustomFields: source.CustomFields,
+		WorkspaceID:  source.WorkspaceID,
+		UserID:       userID,
+	}
+	if project.Name == "" {
+		project.Name = source.Name + " (copy)"
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+
+		milestoneIDs := make(map[uint]uint)
+		for _, m := range source.Milestones {
+			milestone := models.Milestone{
+				Name:        m.Name,
+				Description: m.Description,
+				DueDate:     m.DueDate,
+				Amount:      m.Amount,
+				Status:      models.MilestoneStatusPending,
+				ProjectID:   project.ID,
+				UserID:      userID,
+			}
+			if err := tx.Create(&milestone).Error; err != nil {
+				return err
+			}
+			milestoneIDs[m.ID] = milestone.ID
+		}
+
+		taskIDs := make(map[uint]uint)
+		for _, t := range source.Tasks {
+			task := models.Task{
+				Title:          t.Title,
+				Description:    t.Description,
+				Status:         t.Status,
+				StartedAt:      t.StartedAt,
+				CompletedAt:    t.CompletedAt,
+				Tags:           t.Tags,
+				EstimatedHours: t.EstimatedHours,
+				DueDate:        t.DueDate,
+				Priority:       t.Priority,
+				Position:       t.Position,
+				CustomFields:   t.CustomFields,
+				ProjectID:      project.ID,
+				WorkspaceID:    project.WorkspaceID,
+				UserID:         userID,
+			}
+			if t.NextInstanceID == nil {
+				task.Recurrence, task.RecurrenceStart = t.Recurrence, t.RecurrenceStart
+			}
+			if t.MilestoneID != nil {
+				id := milestoneIDs[*t.MilestoneID]
+				task.MilestoneID = &id
+			}
+			if err := tx.Create(&task).Error; err != nil {
+				return err
+			}
+			taskIDs[t.ID] = task.ID
+
+			for _, item := range t.Checklist {
+				copied := models.TaskChecklistItem{
+					Title:    item.Title,
+					Done:     item.Done,
+					Position: item.Position,
+					TaskID:   task.ID,
+				}
+				if err := tx.Create(&copied).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, t := range source.Tasks {
+			if t.ParentID == nil {
+				continue
+			}
+			if err := tx.Model(&models.Task{}).Where("id = ?", taskIDs[t.ID]).Update("parent_id", taskIDs[*t.ParentID]).Error; err != nil {
+				return err
+			}
+		}
+
+		// Blockers in other projects keep blocking the copies
+		var dependencies []models.TaskDependency
+		err := tx.Where("task_id IN (?) AND blocked_by_id IN (?)",
+			tx.Model(&models.Task{}).Select("id").Where("project_id = ?", source.ID),
+			tx.Model(&models.Task{}).Select("id")).
+			Find(&dependencies).Error
+		if err != nil {
+			return err
+		}
+		for _, d := range dependencies {
+			dependency := models.TaskDependency{TaskID: taskIDs[d.TaskID], BlockedByID: d.BlockedByID}
+			if id, ok := taskIDs[d.BlockedByID]; ok {
+				dependency.BlockedByID = id
+			}
+			if err := tx.Create(&dependency).Error; err != nil {
+				return err
+			}
+		}
+
+		if !req.IncludeTimeEntries {
+			return nil
+		}
+
+		// Entries of people who left the source project stay behind. The
+		// other authors join the copy with their role, capped at manager.
+		var members []models.ProjectMember
+		if err := tx.Where("project_id = ?", source.ID).Find(&members).Error; err != nil {
+			return err
+		}
+		authors := make(map[uint]*models.ProjectMember)
+		for i := range members {
+			authors[members[i].UserID] = &members[i]
+		}
+
+		var entries []models.TimeEntry
+		if err := tx.Preload("Tags").Where("project_id = ? AND running = ?", source.ID, false).Find(&entries).Error; err != nil {
+			return err
+		}
+		joined := map[uint]bool{userID: true}
+		for _, e := range entries {
+			if !joined[e.UserID] {
+				m, ok := authors[e.UserID]
+				if !ok {
+					continue
+				}
+				member := models.ProjectMember{ProjectID: project.ID, UserID: m.UserID, Role: m.Role, CostRate: m.CostRate}
+				if member.Role == models.ProjectRoleOwner {
+					member.Role = models.ProjectRoleManager
+				}
+				if err := tx.Create(&member).Error; err != nil {
+					return err
+				}
+				joined[e.UserID] = true
+			}
+
+			entry := models.TimeEntry{
+				StartTime:    e.StartTime,
+				EndTime:      e.EndTime,
+				Duration:     e.Duration,
+				Notes:        e.Notes,
+				ProjectID:    project.ID,
+				TaskID:       taskIDs[e.TaskID],
+				CustomFields: e.CustomFields,
+				Tags:         e.Tags,
+				WorkspaceID:  project.WorkspaceID,
+				UserID:       e.UserID,
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error duplicating project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// bindOptionalJSON binds the request body like ShouldBindJSON, but treats a
+// missing body as an empty object.
+func bindOptionalJSON(c *gin.Context, obj any) error {
+	if err := c.ShouldBindJSON(obj); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// setProjectCurrency validates the project currency, defaulting to the
+// owner's base currency when none is given.
+func setProjectCurrency(project *models.Project) error {
+	if project.Currency == "" {
+		var user models.User
+		if err := database.DB.First(&user, project.UserID).Error; err != nil {
+			return err
+		}
+		project.Currency = user.BaseCurrency
+		return nil
+	}
+
+	currency, err := exchange.NormalizeCurrency(project.Currency)
+	if err != nil {
+		return err
+	}
+	project.Currency = currency
+	return nil
+}
+
+// validateProjectParent checks that a project's parent is a project in the
+// same workspace that the caller manages, and that it is not the project
+// itself or one of its sub-projects.
+func validateProjectParent(c *gin.Context, project *models.Project) error {
+	if project.ParentID == nil {
+		return nil
+	}
+
+	var parent models.Project
+	err := database.DB.Where("id = ? AND workspace_id = ?", *project.ParentID, project.WorkspaceID).First(&parent).Error
+	if err != nil || !hasProjectRole(c, parent.ID, models.ProjectRoleManager) {
+		return errors.New("Parent project not found")
+	}
+
+	if project.ID == 0 {
+		return nil
+	}
+	subtree, err := projectSubtreeIDs(project.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range subtree {
+		if id == parent.ID {
+			return errors.New("A project cannot be moved under itself or one of its sub-projects")
+		}
+	}
+	return nil
+}
+
+// projectSubtreeIDs returns the project and all of its descendants.
+func projectSubtreeIDs(rootID uint) ([]uint, error) {
+	var ids []uint
+	err := database.DB.Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM projects WHERE id = ? AND deleted_at IS NULL
+			UNION
+			SELECT p.id FROM projects p JOIN tree t ON p.parent_id = t.id WHERE p.deleted_at IS NULL
+		)
+		SELECT id FROM tree`, rootID).Scan(&ids).Error
+	return ids, err
+}
+
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+)
+
+func TestDuplicateProject(t *testing.T) {
+	user, project := testProject(t)
+	_, other := testProject(t)
+
+	tasks := make([]models.Task, 3)
+	for i := range tasks {
+		tasks[i] = models.Task{
+			Title:       fmt.Sprintf("Task %d", i),
+			Status:      models.TaskStatusTodo,
+			Priority:    models.TaskPriorityMedium,
+			Position:    2 - i,
+			ProjectID:   project.ID,
+			WorkspaceID: project.WorkspaceID,
+			UserID:      user.ID,
+		}
+		if err := database.DB.Create(&tasks[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	blocker := models.Task{Title: "Elsewhere", Status: models.TaskStatusTodo, ProjectID: other.ID, WorkspaceID: other.WorkspaceID, UserID: other.UserID}
+	if err := database.DB.Create(&blocker).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []models.TaskDependency{
+		{TaskID: tasks[1].ID, BlockedByID: tasks[0].ID},
+		{TaskID: tasks[2].ID, BlockedByID: blocker.ID},
+	} {
+		if err := database.DB.Create(&d).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No body at all
+	c, w := testContext(user, http.MethodPost, fmt.Sprintf("/projects/%d/duplicate", project.ID), nil, "id", fmt.Sprint(project.ID))
+	DuplicateProject(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("DuplicateProject: %d %s", w.Code, w.Body)
+	}
+	var copied models.Project
+	if err := json.NewDecoder(w.Body).Decode(&copied); err != nil {
+		t.Fatal(err)
+	}
+	if want := project.Name + " (copy)"; copied.Name != want {
+		t.Errorf("name = %q, want %q", copied.Name, want)
+	}
+
+	var copies []models.Task
+	if err := database.DB.Where("project_id = ?", copied.ID).Order("title").Find(&copies).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(copies) != len(tasks) {
+		t.Fatalf("copied %d tasks, want %d", len(copies), len(tasks))
+	}
+	for i, task := range copies {
+		if task.Position != tasks[i].Position {
+			t.Errorf("%s position = %d, want %d", task.Title, task.Position, tasks[i].Position)
+		}
+	}
+
+	var dependencies []models.TaskDependency
+	if err := database.DB.Where("task_id IN (?)", []uint{copies[0].ID, copies[1].ID, copies[2].ID}).Order("task_id").Find(&dependencies).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []models.TaskDependency{
+		{TaskID: copies[1].ID, BlockedByID: copies[0].ID},
+		{TaskID: copies[2].ID, BlockedByID: blocker.ID},
+	}
+	if len(dependencies) != len(want) {
+		t.Fatalf("copied %d dependencies, want %d", len(dependencies), len(want))
+	}
+	for i, d := range dependencies {
+		if d.TaskID != want[i].TaskID || d.BlockedByID != want[i].BlockedByID {
+			t.Errorf("dependency %d blocked by %d, want %d blocked by %d", d.TaskID, d.BlockedByID, want[i].TaskID, want[i].BlockedByID)
+		}
+	}
+
+	c, w = testContext(user, http.MethodPost, fmt.Sprintf("/projects/%d/duplicate", project.ID), strings.NewReader(`{"name": 1}`), "id", fmt.Sprint(project.ID))
+	DuplicateProject(c)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("DuplicateProject with an invalid body: %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDuplicateProjectTimeEntries(t *testing.T) {
+	user, project := testProject(t)
+	member, _ := testProject(t)
+	former, _ := testProject(t)
+
+	if err := database.DB.Create(&models.ProjectMember{ProjectID: project.ID, UserID: member.ID, Role: models.ProjectRoleMember}).Error; err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-2 * time.Hour)
+	for _, e := range []models.TimeEntry{
+		{Notes: "Design", UserID: user.ID},
+		{Notes: "Still going", Running: true, UserID: user.ID},
+		{Notes: "Review", UserID: member.ID},
+		{Notes: "Old work", UserID: former.ID},
+	} {
+		e.StartTime, e.EndTime, e.Duration = start, start.Add(time.Hour), 3600
+		e.ProjectID, e.WorkspaceID = project.ID, project.WorkspaceID
+		if err := database.DB.Create(&e).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, w := testContext(user, http.MethodPost, fmt.Sprintf("/projects/%d/duplicate", project.ID), strings.NewReader(`{"include_time_entries": true}`), "id", fmt.Sprint(project.ID))
+	DuplicateProject(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("DuplicateProject: %d %s", w.Code, w.Body)
+	}
+	var copied models.Project
+	if err := json.NewDecoder(w.Body).Decode(&copied); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []models.TimeEntry
+	if err := database.DB.Where("project_id = ?", copied.ID).Order("notes").Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Notes != "Design" || entries[1].Notes != "Review" {
+		t.Fatalf("copied entries %+v, want Design and Review", entries)
+	}
+	if entries[0].Duration != 3600 || entries[1].UserID != member.ID {
+		t.Errorf("copied entries %+v lost their duration or author", entries)
+	}
+	var membership models.ProjectMember
+	if err := database.DB.Where("project_id = ? AND user_id = ?", copied.ID, member.ID).First(&membership).Error; err != nil {
+		t.Fatalf("entry author is not a member of the copy: %v", err)
+	}
+	if membership.Role != models.ProjectRoleMember {
+		t.Errorf("entry author role in the copy = %q, want %q", membership.Role, models.ProjectRoleMember)
+	}
+}
+
+func TestCreateProjectAssociations(t *testing.T) {
+	user, _ := testProject(t)
+
+	body := `{"name": "Injected", "tasks": [{"title": "Task"}], "milestones": [{"name": "Beta"}],
+		"time_entries": [{"start_time": "2024-01-01T09:00:00Z", "end_time": "2024-01-01T17:00:00Z", "duration": 28800}]}`
+	c, w := testContext(user, http.MethodPost, "/projects", strings.NewReader(body))
+	CreateProject(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateProject: %d %s", w.Code, w.Body)
+	}
+	var project models.Project
+	if err := json.NewDecoder(w.Body).Decode(&project); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, model := range []any{&models.Task{}, &models.Milestone{}, &models.TimeEntry{}} {
+		var count int64
+		database.DB.Model(model).Where("project_id = ?", project.ID).Count(&count)
+		if count != 0 {
+			t.Errorf("%d %T rows created through the request body", count, model)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/rrule"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RecurrenceRequest struct {
+	Recurrence string `json:"recurrence"`
+}
+
+type UpcomingOccurrence struct {
+	TaskID    uint      `json:"task_id"` // latest instance of the series
+	SeriesID  uint      `json:"series_id"`
+	Title     string    `json:"title"`
+	ProjectID uint      `json:"project_id"`
+	DueDate   time.Time `json:"due_date"`
+	Created   bool      `json:"created"` // whether the instance already exists
+}
+
+// SetTaskRecurrence sets the RRULE of a task, or ends its series when the rule
+// is empty. Only the latest instance of a series can change it, and the rule
+// is expanded from the task's due date.
+func SetTaskRecurrence(c *gin.Context) {
+	var req RecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, ok := findTaskWithRole(c, models.ProjectRoleMember)
+	if !ok {
+		return
+	}
+
+	if task.NextInstanceID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only the latest instance of a series can change its recurrence"})
+		return
+	}
+
+	if err := setTaskRecurrence(&task, req.Recurrence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Model(&task).Updates(map[string]interface{}{
+		"recurrence":       task.Recurrence,
+		"recurrence_start": task.RecurrenceStart,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// GetUpcomingOccurrences lists the occurrences of the caller's recurring tasks
+// over the next ?days= (default 30) in date order, both the instances that
+// already exist and those still to be created. ?project_id= narrows it to one
+// project.
+func GetUpcomingOccurrences(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 366 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 366"})
+		return
+	}
+
+	query := database.DB.Where("project_id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).
+		Where("recurrence <> '' AND next_instance_id IS NULL AND due_date IS NOT NULL")
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var tasks []models.Task
+	if err := query.Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+
+	now := time.Now()
+	until := now.AddDate(0, 0, days)
+	occurrences := []UpcomingOccurrence{}
+	for _, task := range tasks {
+		occurrence := UpcomingOccurrence{
+			TaskID:    task.ID,
+			SeriesID:  task.ID,
+			Title:     task.Title,
+			ProjectID: task.ProjectID,
+		}
+		if task.SeriesID != nil {
+			occurrence.SeriesID = *task.SeriesID
+		}
+
+		if !task.DueDate.Before(now) && !task.DueDate.After(until) {
+			occurrence.DueDate, occurrence.Created = *task.DueDate, true
+			occurrences = append(occurrences, occurrence)
+		}
+
+		rule, err := rrule.Parse(task.Recurrence)
+		if err != nil || task.RecurrenceStart == nil {
+			continue
+		}
+		occurrence.Created = false
+		for _, date := range rule.Between(*task.RecurrenceStart, latest(*task.DueDate, now), until, 100) {
+			occurrence.DueDate = date
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool {
+		if !occurrences[i].DueDate.Equal(occurrences[j].DueDate) {
+			return occurrences[i].DueDate.Before(occurrences[j].DueDate)
+		}
+		return occurrences[i].TaskID < occurrences[j].TaskID
+	})
+
+	c.JSON(http.StatusOK, occurrences)
+}
+
+// SpawnDueRecurringTasks creates the next instance of every recurring task
+// that has fallen due without one. It runs from the scheduler.
+func SpawnDueRecurringTasks() error {
+	var ids []uint
+	err := database.DB.Model(&models.Task{}).
+		Where("recurrence <> '' AND next_instance_id IS NULL AND due_date <= ?", time.Now()).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			next, err := spawnNextInstance(tx, id)
+			if err != nil || next == nil {
+				return err
+			}
+			return syncParentStatus(tx, next.ParentID)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("task %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setTaskRecurrence validates and normalizes rule and anchors the series at
+// the task's due date. An empty rule clears the recurrence.
+func setTaskRecurrence(task *models.Task, rule string) error {
+	if strings.TrimSpace(rule) == "" {
+		task.Recurrence, task.RecurrenceStart = "", nil
+		return nil
+	}
+	if task.DueDate == nil {
+		return errors.New("Recurring tasks need a due date")
+	}
+
+	parsed, err := rrule.Parse(rule)
+	if err != nil {
+		return fmt.Errorf("Invalid recurrence: %v", err)
+	}
+	task.Recurrence = parsed.String()
+	task.RecurrenceStart = task.DueDate
+	return nil
+}
+
+// spawnNextInstance creates the instance of a recurring task for the first
+// occurrence after its due date, or after now when it is overdue, so missed
+// occurrences are skipped. Each instance spawns at most one successor; nil is
+// returned when there is nothing to create or the series has ended. Callers
+// sync the new instance's parent.
+func spawnNextInstance(tx *gorm.DB, taskID uint) (*models.Task, error) {
+	// The project is locked before the task, in the order every other
+	// position change takes them
+	var task models.Task
+	if err := tx.Select("project_id").First(&task, taskID).Error; err != nil {
+		return nil, err
+	}
+	if err := lockTaskPositions(tx, task.ProjectID); err != nil {
+		return nil, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task, taskID).Error; err != nil {
+		return nil, err
+	}
+	if task.Recurrence == "" || task.NextInstanceID != nil || task.DueDate == nil || task.RecurrenceStart == nil {
+		return nil, nil
+	}
+
+	rule, err := rrule.Parse(task.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	due, ok := rule.After(*task.RecurrenceStart, latest(*task.DueDate, time.Now()))
+	if !ok {
+		return nil, nil
+	}
+
+	if err := tx.Model(&task).Association("Tags").Find(&task.Tags); err != nil {
+		return nil, err
+	}
+	var checklist []models.TaskChecklistItem
+	if err := tx.Where("task_id = ?", task.ID).Order("position, id").Find(&checklist).Error; err != nil {
+		return nil, err
+	}
+
+	seriesID := task.ID
+	if task.SeriesID != nil {
+		seriesID = *task.SeriesID
+	}
+	next := models.Task{
+		Title:           task.Title,
+		Description:     task.Description,
+		Status:          models.TaskStatusTodo,
+		Tags:            task.Tags,
+		EstimatedHours:  task.EstimatedHours,
+		DueDate:         &due,
+		Priority:        task.Priority,
+		ProjectID:       task.ProjectID,
+		ParentID:        task.ParentID,
+		CustomFields:    task.CustomFields,
+		WorkspaceID:     task.WorkspaceID,
+		UserID:          task.UserID,
+		Recurrence:      task.Recurrence,
+		RecurrenceStart: task.RecurrenceStart,
+		SeriesID:        &seriesID,
+	}
+	for _, item := range checklist {
+		next.Checklist = append(next.Checklist, models.TaskChecklistItem{Title: item.Title, Position: item.Position})
+	}
+
+	if err := nextTaskPosition(tx, next.ProjectID, next.Status).Scan(&next.Position).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&next).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&task).Update("next_instance_id", next.ID).Error; err != nil {
+		return nil, err
+	}
+
+	return &next, nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
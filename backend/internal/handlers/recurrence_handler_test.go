@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TestSpawnNextInstance checks that an overdue recurring task spawns the
+// first occurrence after now rather than the missed ones.
+func TestSpawnNextInstance(t *testing.T) {
+	user, project := testProject(t)
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, time.UTC)
+	nextDaily := today
+	if !today.After(now) {
+		nextDaily = today.AddDate(0, 0, 1)
+	}
+
+	tests := []struct {
+		name       string
+		recurrence string
+		due        time.Time
+		want       *time.Time // nil when no instance should spawn
+	}{
+		{"missed days are skipped", "FREQ=DAILY", today.AddDate(0, 0, -10), &nextDaily},
+		{"missed weeks are skipped", "FREQ=WEEKLY;INTERVAL=2", today.AddDate(0, 0, -7*5), ptrTime(today.AddDate(0, 0, 7))},
+		{"not yet due", "FREQ=DAILY", today.AddDate(0, 0, 3), ptrTime(today.AddDate(0, 0, 4))},
+		{"series ended while overdue", "FREQ=DAILY;COUNT=3", today.AddDate(0, 0, -10), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := tt.due
+			task := models.Task{
+				Title:           tt.name,
+				Status:          models.TaskStatusCompleted,
+				Priority:        models.TaskPriorityMedium,
+				DueDate:         &due,
+				Recurrence:      tt.recurrence,
+				RecurrenceStart: &due,
+				ProjectID:       project.ID,
+				WorkspaceID:     project.WorkspaceID,
+				UserID:          user.ID,
+			}
+			if err := database.DB.Create(&task).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			var next, again *models.Task
+			err := database.DB.Transaction(func(tx *gorm.DB) error {
+				var err error
+				if next, err = spawnNextInstance(tx, task.ID); err != nil {
+					return err
+				}
+				again, err = spawnNextInstance(tx, task.ID)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want == nil {
+				if next != nil {
+					t.Fatalf("spawned an instance due %v", next.DueDate)
+				}
+				return
+			}
+			if next == nil {
+				t.Fatal("no instance spawned")
+			}
+			if !next.DueDate.Equal(*tt.want) {
+				t.Errorf("next due %v, want %v", next.DueDate.UTC(), tt.want.UTC())
+			}
+			if next.Status != models.TaskStatusTodo || next.SeriesID == nil || *next.SeriesID != task.ID {
+				t.Errorf("next instance: status %s, series %v", next.Status, next.SeriesID)
+			}
+			if again != nil {
+				t.Errorf("spawned a second instance %d", again.ID)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/exchange"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfitabilityRow struct {
+	ID                  uint    `json:"id"`
+	Name                string  `json:"name"`
+	Hours               float64 `json:"hours"`
+	Revenue             float64 `json:"revenue"`
+	Cost                float64 `json:"cost"`
+	Margin              float64 `json:"margin"`
+	MarginPercent       float64 `json:"marginPercent"`
+	EffectiveHourlyRate float64 `json:"effectiveHourlyRate"`
+}
+
+type ProfitabilityResponse struct {
+	Currency     string             `json:"currency"`
+	StartDate    string             `json:"startDate"`
+	EndDate      string             `json:"endDate"`
+	GroupBy      string             `json:"groupBy"`
+	Rows         []ProfitabilityRow `json:"rows"`
+	Total        ProfitabilityRow   `json:"total"`
+	MissingRates []string           `json:"missingRates,omitempty"`
+}
+
+// GetProfitabilityReport compares revenue with internal cost per project or
+// per client (?group_by=client) over the date range. Hourly work is billed at
+// the project rate, except on tasks of fixed-price milestones, which earn the
+// milestone amount when the milestone is completed within the range. Cost uses
+// the member's project cost rate, falling back to the user's own cost rate.
+// Only projects the caller manages are included.
+func GetProfitabilityReport(c *gin.Context) {
+	userID := utils.GetUserID(c)
+
+	startDate, endDate, err := parseDateRange(c, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rangeEnd := endDate.AddDate(0, 0, 1)
+
+	groupBy := c.DefaultQuery("group_by", "project")
+	if groupBy != "project" && groupBy != "client" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be project or client"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	currency := user.BaseCurrency
+	if c.Query("currency") != "" {
+		currency, err = exchange.NormalizeCurrency(c.Query("currency"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var projects []models.Project
+	if err := database.DB.Where("id IN (?)", memberProjectIDs(c, models.ProjectRoleManager)).Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching projects"})
+		return
+	}
+
+	projectIDs := make([]uint, len(projects))
+	projectsByID := make(map[uint]models.Project)
+	for i, p := range projects {
+		projectIDs[i] = p.ID
+		projectsByID[p.ID] = p
+	}
+
+	var entries []models.TimeEntry
+	err = database.DB.Where("project_id IN ? AND start_time >= ? AND start_time < ?", projectIDs, startDate, rangeEnd).
+		Find(&entries).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching time entries"})
+		return
+	}
+
+	var milestones []models.Milestone
+	err = database.DB.Where("project_id IN ? AND amount > 0", projectIDs).Find(&milestones).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching milestones"})
+		return
+	}
+
+	fixedPriceTasks := make(map[uint]bool)
+	if len(milestones) > 0 {
+		milestoneIDs := make([]uint, len(milestones))
+		for i, m := range milestones {
+			milestoneIDs[i] = m.ID
+		}
+		var taskIDs []uint
+		if err := database.DB.Model(&models.Task{}).Where("milestone_id IN ?", milestoneIDs).Pluck("id", &taskIDs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+			return
+		}
+		for _, id := range taskIDs {
+			fixedPriceTasks[id] = true
+		}
+	}
+
+	var members []models.ProjectMember
+	if err := database.DB.Where("project_id IN ?", projectIDs).Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching members"})
+		return
+	}
+	memberRates := make(map[[2]uint]float64)
+	for _, m := range members {
+		if m.CostRate != nil {
+			memberRates[[2]uint{m.ProjectID, m.UserID}] = *m.CostRate
+		}
+	}
+
+	var workers []models.User
+	err = database.DB.Where("id IN (?)", database.DB.Model(&models.TimeEntry{}).Select("DISTINCT user_id").Where("project_id IN ?", projectIDs)).
+		Find(&workers).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching users"})
+		return
+	}
+	workersByID := make(map[uint]models.User)
+	for _, w := range workers {
+		workersByID[w.ID] = w
+	}
+
+	converter, err := loadConverter(utils.GetWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching exchange rates"})
+		return
+	}
+
+	missing := make(map[string]bool)
+	convert := func(amount float64, from string, date time.Time) float64 {
+		if amount == 0 {
+			return 0
+		}
+		value, err := converter.Convert(amount, from, currency, date)
+		if err != nil {
+			missing[from+"/"+currency+" "+date.Format("2006-01-02")] = true
+			return 0
+		}
+		return value
+	}
+
+	rows := make(map[uint]*ProfitabilityRow)
+	rowFor := func(project models.Project) *ProfitabilityRow {
+		key, name := project.ID, project.Name
+		if groupBy == "client" {
+			key, name = 0, "No client"
+			if project.ClientID != nil {
+				key = *project.ClientID
+			}
+		}
+		if rows[key] == nil {
+			rows[key] = &ProfitabilityRow{ID: key, Name: name}
+		}
+		return rows[key]
+	}
+
+	for _, entry := range entries {
+		project := projectsByID[entry.ProjectID]
+		row := rowFor(project)
+		hours := float64(entry.Duration) / 3600
+		row.Hours += hours
+
+		if !fixedPriceTasks[entry.TaskID] {
+			row.Revenue += convert(hours*project.HourlyRate, project.Currency, entry.StartTime)
+		}
+
+		if rate, ok := memberRates[[2]uint{project.ID, entry.UserID}]; ok {
+			row.Cost += convert(hours*rate, project.Currency, entry.StartTime)
+		} else if worker, ok := workersByID[entry.UserID]; ok {
+			row.Cost += convert(hours*worker.CostRate, worker.BaseCurrency, entry.StartTime)
+		}
+	}
+
+	for _, m := range milestones {
+		if m.Status != models.MilestoneStatusCompleted || m.CompletedAt == nil {
+			continue
+		}
+		if m.CompletedAt.Before(startDate) || !m.CompletedAt.Before(rangeEnd) {
+			continue
+		}
+		project := projectsByID[m.ProjectID]
+		rowFor(project).Revenue += convert(m.Amount, project.Currency, *m.CompletedAt)
+	}
+
+	if groupBy == "client" {
+		var clients []models.Client
+		database.DB.Where("workspace_id = ?", utils.GetWorkspaceID(c)).Find(&clients)
+		for _, client := range clients {
+			if row := rows[client.ID]; row != nil {
+				row.Name = client.Name
+			}
+		}
+	}
+
+	response := ProfitabilityResponse{
+		Currency:  currency,
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		GroupBy:   groupBy,
+		Rows:      []ProfitabilityRow{},
+		Total:     ProfitabilityRow{Name: "Total"},
+	}
+	for _, row := range rows {
+		finishProfitabilityRow(row)
+		response.Rows = append(response.Rows, *row)
+		response.Total.Hours += row.Hours
+		response.Total.Revenue += row.Revenue
+		response.Total.Cost += row.Cost
+	}
+	finishProfitabilityRow(&response.Total)
+	sort.Slice(response.Rows, func(i, j int) bool { return response.Rows[i].Margin > response.Rows[j].Margin })
+
+	for key := range missing {
+		response.MissingRates = append(response.MissingRates, key)
+	}
+	sort.Strings(response.MissingRates)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func finishProfitabilityRow(row *ProfitabilityRow) {
+	row.Margin = row.Revenue - row.Cost
+	if row.Revenue != 0 {
+		row.MarginPercent = row.Margin / row.Revenue * 100
+	}
+	if row.Hours != 0 {
+		row.EffectiveHourlyRate = row.Revenue / row.Hours
+	}
+}
+
+type EstimateRow struct {
+	ID              uint    `json:"id,omitempty"`
+	Name            string  `json:"name"`
+	Tasks           int     `json:"tasks"`
+	EstimatedHours  float64 `json:"estimatedHours"`
+	TrackedHours    float64 `json:"trackedHours"`
+	VarianceHours   float64 `json:"varianceHours"`
+	VariancePercent float64 `json:"variancePercent"`
+}
+
+type EstimateReportResponse struct {
+	Tasks    []EstimateRow `json:"tasks"`
+	Projects []EstimateRow `json:"projects"`
+	Tags     []EstimateRow `json:"tags"`
+	Total    EstimateRow   `json:"total"`
+}
+
+// GetEstimateReport compares estimated with tracked hours for every estimated
+// task, and summed per project and per tag. Variance is tracked minus
+// estimated; a positive percentage means the work took longer than quoted.
+// Tracked hours include subtasks, so a task is left out of the project, tag and
+// total sums when one of its ancestors is already counted there. Filter with
+// ?project_id= and ?status=.
+func GetEstimateReport(c *gin.Context) {
+	query := database.DB.Where("project_id IN (?) AND estimated_hours > 0", memberProjectIDs(c, models.ProjectRoleViewer))
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	query = applyTagFilter(c, query, "tasks", "task_tags", "task_id")
+
+	var tasks []models.Task
+	if err := query.Preload("Tags").Order("id").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+	if err := loadTaskHours(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching time entries"})
+		return
+	}
+
+	var projects []models.Project
+	database.DB.Where("id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).Find(&projects)
+	projectNames := make(map[uint]string)
+	for _, p := range projects {
+		projectNames[p.ID] = p.Name
+	}
+
+	var links []struct {
+		ID       uint
+		ParentID uint
+	}
+	database.DB.Model(&models.Task{}).
+		Select("id, parent_id").
+		Where("parent_id IS NOT NULL AND project_id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)).
+		Scan(&links)
+	parentOf := make(map[uint]uint)
+	for _, l := range links {
+		parentOf[l.ID] = l.ParentID
+	}
+	estimated := make(map[uint]bool)
+	for _, task := range tasks {
+		estimated[task.ID] = true
+	}
+	counted := func(taskID uint) bool {
+		seen := map[uint]bool{taskID: true}
+		for id := parentOf[taskID]; id != 0 && !seen[id]; id = parentOf[id] {
+			if estimated[id] {
+				return true
+			}
+			seen[id] = true
+		}
+		return false
+	}
+
+	response := EstimateReportResponse{
+		Tasks:    []EstimateRow{},
+		Projects: []EstimateRow{},
+		Tags:     []EstimateRow{},
+		Total:    EstimateRow{Name: "Total"},
+	}
+	byProject := make(map[uint]*EstimateRow)
+	byTag := make(map[uint]*EstimateRow)
+
+	add := func(row *EstimateRow, task models.Task) {
+		row.Tasks++
+		row.EstimatedHours += task.EstimatedHours
+		row.TrackedHours += task.TrackedHours
+	}
+
+	for _, task := range tasks {
+		row := EstimateRow{ID: task.ID, Name: task.Title}
+		add(&row, task)
+		finishEstimateRow(&row)
+		response.Tasks = append(response.Tasks, row)
+
+		if counted(task.ID) {
+			continue
+		}
+
+		if byProject[task.ProjectID] == nil {
+			byProject[task.ProjectID] = &EstimateRow{ID: task.ProjectID, Name: projectNames[task.ProjectID]}
+		}
+		add(byProject[task.ProjectID], task)
+
+		for _, tag := range task.Tags {
+			if byTag[tag.ID] == nil {
+				byTag[tag.ID] = &EstimateRow{ID: tag.ID, Name: tag.Name}
+			}
+			add(byTag[tag.ID], task)
+		}
+
+		add(&response.Total, task)
+	}
+
+	for _, row := range byProject {
+		finishEstimateRow(row)
+		response.Projects = append(response.Projects, *row)
+	}
+	sort.Slice(response.Projects, func(i, j int) bool { return response.Projects[i].ID < response.Projects[j].ID })
+
+	for _, row := range byTag {
+		finishEstimateRow(row)
+		response.Tags = append(response.Tags, *row)
+	}
+	sort.Slice(response.Tags, func(i, j int) bool { return response.Tags[i].Name < response.Tags[j].Name })
+
+	finishEstimateRow(&response.Total)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func finishEstimateRow(row *EstimateRow) {
+	row.VarianceHours = row.TrackedHours - row.EstimatedHours
+	if row.EstimatedHours != 0 {
+		row.VariancePercent = row.VarianceHours / row.EstimatedHours * 100
+	}
+}
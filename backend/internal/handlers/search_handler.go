@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	SearchTypeProject   = "project"
+	SearchTypeTask      = "task"
+	SearchTypeTimeEntry = "time_entry"
+)
+
+type SearchResult struct {
+	Type      string     `json:"type"`
+	ID        uint       `json:"id"`
+	Title     string     `json:"title"`
+	Snippet   string     `json:"snippet"` // HTML with the matches in <b>
+	ProjectID uint       `json:"project_id"`
+	Date      *time.Time `json:"date,omitempty"` // start of a time entry
+	Rank      float64    `json:"rank"`
+}
+
+// searchQueries select type, id, title, snippet, project_id, rank and
+// optionally date for one result type. The placeholders are the headline
+// options, the search text, the IDs the caller may see and the limit.
+var searchQueries = map[string]string{
+	SearchTypeProject: `
+		SELECT 'project' AS type, p.id, p.name AS title, p.id AS project_id,
+			ts_headline(?, ` + htmlEscaped("COALESCE(NULLIF(p.description, ''), p.name)") + `, q, ?) AS snippet,
+			ts_rank(p.search_vector, q) AS rank
+		FROM projects p CROSS JOIN websearch_to_tsquery(?, ?) q
+		WHERE p.search_vector @@ q AND p.deleted_at IS NULL AND p.id IN (?)
+		ORDER BY rank DESC, p.id LIMIT ?`,
+	// Tags are weighted like titles
+	SearchTypeTask: `
+		SELECT 'task' AS type, t.id, t.title, t.project_id,
+			ts_headline(?, ` + htmlEscaped("COALESCE(NULLIF(t.description, ''), t.title)") + `, q, ?) AS snippet,
+			ts_rank(d.doc, q) AS rank
+		FROM tasks t CROSS JOIN websearch_to_tsquery(?, ?) q
+		CROSS JOIN LATERAL (
+			SELECT t.search_vector || setweight(to_tsvector('` + database.SearchConfig + `', COALESCE(string_agg(tags.name, ' '), '')), 'A') AS doc
+			FROM task_tags JOIN tags ON tags.id = task_tags.tag_id AND tags.deleted_at IS NULL
+			WHERE task_tags.task_id = t.id
+		) d
+		WHERE d.doc @@ q AND t.deleted_at IS NULL AND t.id IN (?)
+		ORDER BY rank DESC, t.id LIMIT ?`,
+	SearchTypeTimeEntry: `
+		SELECT 'time_entry' AS type, e.id, COALESCE(t.title, p.name) AS title, e.project_id, e.start_time AS date,
+			ts_headline(?, ` + htmlEscaped("e.notes") + `, q, ?) AS snippet,
+			ts_rank(e.search_vector, q) AS rank
+		FROM time_entries e
+		JOIN projects p ON p.id = e.project_id
+		LEFT JOIN tasks t ON t.id = e.task_id AND t.deleted_at IS NULL
+		CROSS JOIN websearch_to_tsquery(?, ?) q
+		WHERE e.search_vector @@ q AND e.deleted_at IS NULL AND e.id IN (?)
+		ORDER BY rank DESC, e.id LIMIT ?`,
+}
+
+const searchHeadlineOptions = "MaxFragments=2, MinWords=5, MaxWords=20, FragmentDelimiter=\" … \""
+
+// Search runs a full-text query (?q=, web search syntax: quoted phrases, OR,
+// -excluded) over project names and descriptions, task titles, descriptions
+// and tags, and time entry notes the caller can see. Results of all types are
+// ranked together; ?type= limits them to a comma separated list of project,
+// task and time_entry, and ?limit= (default 20, at most 100) caps them.
+func Search(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	types := []string{SearchTypeProject, SearchTypeTask, SearchTypeTimeEntry}
+	if v := c.Query("type"); v != "" {
+		types = strings.Split(v, ",")
+		for _, t := range types {
+			if searchQueries[t] == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid type %q", t)})
+				return
+			}
+		}
+	}
+
+	visible := map[string]*gorm.DB{
+		SearchTypeProject:   memberProjectIDs(c, models.ProjectRoleViewer),
+		SearchTypeTask:      database.DB.Model(&models.Task{}).Select("id").Where("project_id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer)),
+		SearchTypeTimeEntry: visibleTimeEntries(c).Model(&models.TimeEntry{}).Select("id"),
+	}
+
+	results := []SearchResult{}
+	for _, t := range types {
+		var rows []SearchResult
+		err := database.DB.Raw(searchQueries[t],
+			database.SearchConfig, searchHeadlineOptions, database.SearchConfig, text, visible[t], limit).
+			Scan(&rows).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching"})
+			return
+		}
+		results = append(results, rows...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// htmlEscaped wraps an SQL text expression so its headline can be shown as
+// HTML without letting user content through as markup.
+func htmlEscaped(expr string) string {
+	return "replace(replace(replace(" + expr + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// tagJoinTables are the many2many tables linking tags to tagged rows.
+var tagJoinTables = []struct {
+	table  string
+	column string
+}{
+	{"task_tags", "task_id"},
+	{"time_entry_tags", "time_entry_id"},
+	{"project_template_task_tags", "project_template_task_id"},
+}
+
+type TagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type MergeTagRequest struct {
+	IntoID uint `json:"into_id" binding:"required"`
+}
+
+var tagSorts = listSorts{"name": "LOWER(name)", "created_at": "created_at"}
+
+func GetTags(c *gin.Context) {
+	list, err := parseListQuery(c, "tags", tagSorts, "name", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tags []models.Tag
+	if err := list.apply(database.DB.Where("workspace_id = ?", utils.GetWorkspaceID(c))).Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tags"})
+		return
+	}
+	tags = page(c, list, tags, func(t models.Tag) uint { return t.ID })
+
+	c.JSON(http.StatusOK, tags)
+}
+
+func CreateTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag := models.Tag{
+		Name:        normalizeTagName(req.Name),
+		Color:       req.Color,
+		WorkspaceID: utils.GetWorkspaceID(c),
+		UserID:      utils.GetUserID(c),
+	}
+	if tag.Color == "" {
+		tag.Color = models.DefaultTagColor
+	}
+	if err := validateTag(tag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, found := findTagByName(tag.WorkspaceID, tag.Name); found {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+		return
+	}
+
+	// The unique index on the lowercased name settles concurrent creates
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating tag"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag renames or recolors a tag. Renaming onto an existing tag's name is
+// refused; merge the tags instead.
+func UpdateTag(c *gin.Context) {
+	tag, ok := findEditableTag(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if name := normalizeTagName(req.Name); name != "" && name != tag.Name {
+		if existing, found := findTagByName(tag.WorkspaceID, name); found && existing.ID != tag.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists, merge the tags instead"})
+			return
+		}
+		tag.Name = name
+	}
+	if req.Color != "" {
+		tag.Color = req.Color
+	}
+	if err := validateTag(tag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Save(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag removes a tag from everything it labels and deletes it.
+func DeleteTag(c *gin.Context) {
+	tag, ok := findEditableTag(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, join := range tagJoinTables {
+			if err := tx.Exec("DELETE FROM "+join.table+" WHERE tag_id = ?", tag.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&tag).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting tag"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MergeTag moves everything labelled with the tag over to into_id and deletes
+// the tag.
+func MergeTag(c *gin.Context) {
+	source, ok := findEditableTag(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var target models.Tag
+	if err := database.DB.Where("id = ? AND workspace_id = ?", req.IntoID, source.WorkspaceID).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target tag not found"})
+		return
+	}
+	if target.ID == source.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a tag into itself"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, join := range tagJoinTables {
+			err := tx.Exec("INSERT INTO "+join.table+" ("+join.column+", tag_id) SELECT "+join.column+", ? FROM "+join.table+" WHERE tag_id = ? ON CONFLICT DO NOTHING",
+				target.ID, source.ID).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM "+join.table+" WHERE tag_id = ?", source.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&source).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error merging tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// findEditableTag loads a tag of the active workspace that the caller created
+// or, as a workspace admin, may manage, writing the error response otherwise.
+func findEditableTag(c *gin.Context, id string) (models.Tag, bool) {
+	var tag models.Tag
+	if err := database.DB.Where("id = ? AND workspace_id = ?", id, utils.GetWorkspaceID(c)).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return tag, false
+	}
+
+	if tag.UserID != utils.GetUserID(c) && !isWorkspaceAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to change this tag"})
+		return tag, false
+	}
+
+	return tag, true
+}
+
+func validateTag(tag models.Tag) error {
+	if tag.Name == "" {
+		return errors.New("Tag name is required")
+	}
+	if len(tag.Name) > 64 {
+		return errors.New("Tag name is too long")
+	}
+	if !tagColorPattern.MatchString(tag.Color) {
+		return errors.New("color must be a hex color like #1D4ED8")
+	}
+	return nil
+}
+
+// normalizeTagName trims a tag name and collapses inner whitespace.
+func normalizeTagName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func findTagByName(workspaceID uint, name string) (models.Tag, bool) {
+	var tag models.Tag
+	err := database.DB.Where("workspace_id = ? AND LOWER(name) = LOWER(?)", workspaceID, name).First(&tag).Error
+	return tag, err == nil
+}
+
+// resolveTags maps the tags of a request onto the workspace's tags. Tags given
+// by ID must exist; tags given by name are matched regardless of case and
+// created through tx when missing.
+func resolveTags(tx *gorm.DB, c *gin.Context, tags []models.Tag) ([]models.Tag, error) {
+	workspaceID := utils.GetWorkspaceID(c)
+	resolved := []models.Tag{}
+	seen := make(map[uint]bool)
+
+	for _, t := range tags {
+		var tag models.Tag
+		if t.ID != 0 {
+			if err := tx.Where("id = ? AND workspace_id = ?", t.ID, workspaceID).First(&tag).Error; err != nil {
+				return nil, errors.New("Tag not found")
+			}
+		} else {
+			name := normalizeTagName(t.Name)
+			if name == "" {
+				continue
+			}
+			byName := func() error {
+				return tx.Where("workspace_id = ? AND LOWER(name) = LOWER(?)", workspaceID, name).First(&tag).Error
+			}
+			err := byName()
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				tag = models.Tag{
+					Name:        name,
+					Color:       models.DefaultTagColor,
+					WorkspaceID: workspaceID,
+					UserID:      utils.GetUserID(c),
+				}
+				if err := validateTag(tag); err != nil {
+					return nil, err
+				}
+				// Another request may create the same tag meanwhile
+				result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag)
+				err = result.Error
+				if err == nil && result.RowsAffected == 0 {
+					tag = models.Tag{}
+					err = byName()
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			resolved = append(resolved, tag)
+		}
+	}
+
+	return resolved, nil
+}
+
+// applyTagFilter narrows a list query to rows carrying any of the tags in
+// ?tag= (comma separated names) or ?tag_id= (comma separated IDs), or all of
+// them with ?tag_match=all.
+func applyTagFilter(c *gin.Context, query *gorm.DB, table, joinTable, column string) *gorm.DB {
+	var names []string
+	for _, name := range strings.Split(c.Query("tag"), ",") {
+		if name = normalizeTagName(name); name != "" {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	var ids []string
+	for _, id := range strings.Split(c.Query("tag_id"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(names) == 0 && len(ids) == 0 {
+		return query
+	}
+
+	tagged := func() *gorm.DB {
+		return database.DB.Table(joinTable).
+			Select(joinTable+"."+column).
+			Joins("JOIN tags ON tags.id = "+joinTable+".tag_id AND tags.deleted_at IS NULL").
+			Where("tags.workspace_id = ?", utils.GetWorkspaceID(c))
+	}
+
+	if c.Query("tag_match") == "all" {
+		for _, name := range names {
+			query = query.Where(table+".id IN (?)", tagged().Where("LOWER(tags.name) = ?", name))
+		}
+		for _, id := range ids {
+			query = query.Where(table+".id IN (?)", tagged().Where("tags.id = ?", id))
+		}
+		return query
+	}
+
+	switch {
+	case len(names) > 0 && len(ids) > 0:
+		return query.Where(table+".id IN (?)", tagged().Where("LOWER(tags.name) IN ? OR tags.id IN ?", names, ids))
+	case len(names) > 0:
+		return query.Where(table+".id IN (?)", tagged().Where("LOWER(tags.name) IN ?", names))
+	default:
+		return query.Where(table+".id IN (?)", tagged().Where("tags.id IN ?", ids))
+	}
+}
@@ -2,25 +2,85 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"timetracker/internal/database"
 	"timetracker/internal/models"
 	"timetracker/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
 )
 
+// taskSorts are the orders GetTasks accepts.
+var taskSorts = listSorts{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"due_date":   "due_date",
+	"priority":   priorityOrder(),
+	"position":   "position",
+	"title":      "LOWER(title)",
+}
+
+// GetTasks lists the tasks of the caller's projects. Besides the project, tag
+// (?tag_match=all for tasks carrying every tag), parent and custom field
+// filters it supports ?status= and ?priority= (comma separated), ?due_before=,
+// ?due_after=, ?overdue=true, ?actionable=true, ?q= matching title and
+// description and ?has_time=true|false, and the list parameters of
+// listQuery with ?sort=created_at|updated_at|due_date|priority|position|title.
 func GetTasks(c *gin.Context) {
-	userID := utils.GetUserID(c)
 	projectID := c.Query("project_id")
 
-	query := database.DB.Where("user_id = ?", userID)
+	query := database.DB.Where("project_id IN (?)", memberProjectIDs(c, models.ProjectRoleViewer))
 	if projectID != "" {
 		query = query.Where("project_id = ?", projectID)
 	}
+	switch parentID := c.Query("parent_id"); parentID {
+	case "":
+	case "none":
+		query = query.Where("parent_id IS NULL")
+	default:
+		query = query.Where("parent_id = ?", parentID)
+	}
+	if c.Query("actionable") == "true" {
+		// Open work that is neither on hold nor waiting on another task
+		query = query.Where("status IN ?", []string{models.TaskStatusTodo, models.TaskStatusInProgress}).
+			Where("id NOT IN (?)", openBlockers().Select("task_dependencies.task_id"))
+	}
+	query = applyCustomFieldFilters(c, query, "tasks")
+	query = applyTagFilter(c, query, "tasks", "task_tags", "task_id")
+
+	query, err := applyTaskScheduleFilters(c, query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query, err = applyTaskContentFilters(c, query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := parseListQuery(c, "tasks", taskSorts, "created_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	var tasks []models.Task
-	if err := query.Find(&tasks).Error; err != nil {
+	if err := list.apply(query).Preload("Tags").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+	tasks = page(c, list, tasks, func(t models.Task) uint { return t.ID })
+
+	if err := loadTaskDetails(tasks); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
 		return
 	}
@@ -30,15 +90,24 @@ func GetTasks(c *gin.Context) {
 
 func GetTask(c *gin.Context) {
 	taskID := c.Param("id")
-	userID := utils.GetUserID(c)
 
 	var task models.Task
-	if err := database.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
+	err := database.DB.Preload("Tags").
+		Preload("Checklist", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Where("id = ? AND project_id IN (?)", taskID, memberProjectIDs(c, models.ProjectRoleViewer)).
+		First(&task).Error
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, task)
+	tasks := []models.Task{task}
+	if err := loadTaskDetails(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks[0])
 }
 
 func CreateTask(c *gin.Context) {
@@ -49,8 +118,80 @@ func CreateTask(c *gin.Context) {
 	}
 
 	task.UserID = utils.GetUserID(c)
+	task.WorkspaceID = utils.GetWorkspaceID(c)
+
+	if task.Priority == "" {
+		task.Priority = models.TaskPriorityMedium
+	}
+	if taskPriorityRank[task.Priority] == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must be LOW, MEDIUM, HIGH or URGENT"})
+		return
+	}
+
+	if task.EstimatedHours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estimated hours cannot be negative"})
+		return
+	}
+
+	status := task.Status
+	if status == "" {
+		status = models.TaskStatusTodo
+	}
+	task.Status, task.StartedAt, task.CompletedAt = "", nil, nil
+	if err := setTaskStatus(&task, status); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !hasProjectRole(c, task.ProjectID, models.ProjectRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to add tasks to this project"})
+		return
+	}
+
+	if task.MilestoneID != nil && !milestoneInProject(*task.MilestoneID, task.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Milestone does not belong to the project"})
+		return
+	}
+
+	if task.ParentID != nil && !taskInProject(*task.ParentID, task.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent task does not belong to the project"})
+		return
+	}
+
+	var err error
+	task.CustomFields, err = validateCustomFields(c, models.CustomFieldEntityTask, task.CustomFields, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task.Tags, err = resolveTags(database.DB, c, task.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := database.DB.Create(&task).Error; err != nil {
+	task.SeriesID, task.NextInstanceID = nil, nil
+	task.ExternalProvider, task.ExternalKey, task.ExternalURL, task.ExternalStatus, task.ExternalSyncedAt = "", "", "", "", nil
+	if err := setTaskRecurrence(&task, task.Recurrence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task.Checklist, task.TimeEntries = nil, nil
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockTaskPositions(tx, task.ProjectID); err != nil {
+			return err
+		}
+		if err := nextTaskPosition(tx, task.ProjectID, task.Status).Scan(&task.Position).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Checklist", "TimeEntries").Create(&task).Error; err != nil {
+			return err
+		}
+		return syncParentStatus(tx, task.ParentID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating task"})
 		return
 	}
@@ -60,25 +201,198 @@ func CreateTask(c *gin.Context) {
 
 func UpdateTask(c *gin.Context) {
 	taskID := c.Param("id")
-	userID := utils.GetUserID(c)
 
 	var task models.Task
-	if err := database.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
+	if err := database.DB.Where("id = ? AND project_id IN (?)", taskID, memberProjectIDs(c, models.ProjectRoleViewer)).First(&task).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
 
+	if !hasProjectRole(c, task.ProjectID, models.ProjectRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to update this task"})
+		return
+	}
+
 	var updates models.Task
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	if err := c.ShouldBindBodyWith(&updates, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// "due_date": null clears the due date, which Updates would skip
+	var nulls struct {
+		DueDate json.RawMessage `json:"due_date"`
+	}
+	if err := c.ShouldBindBodyWith(&nulls, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	clearDueDate := string(nulls.DueDate) == "null"
 
-	if err := database.DB.Model(&task).Updates(updates).Error; err != nil {
+	updates.UserID = 0
+	updates.WorkspaceID = 0
+	updates.StartedAt = nil
+	updates.CompletedAt = nil
+	updates.Checklist = nil
+	updates.Position = 0    // use MoveTask to reorder
+	updates.Recurrence = "" // use SetTaskRecurrence to change the series
+	updates.RecurrenceStart = nil
+	updates.SeriesID = nil
+	updates.NextInstanceID = nil
+	updates.ExternalProvider = "" // use LinkTaskIssue to change the link
+	updates.ExternalKey = ""
+	updates.ExternalURL = ""
+	updates.ExternalStatus = ""
+	updates.ExternalSyncedAt = nil
+
+	tags := updates.Tags
+	updates.Tags = nil
+	if tags != nil {
+		var err error
+		if tags, err = resolveTags(database.DB, c, tags); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	status := updates.Status
+	updates.Status = ""
+	completing := status == models.TaskStatusCompleted && task.Status != models.TaskStatusCompleted
+	if status != "" {
+		if status == models.TaskStatusCompleted && hasOpenSubtasks(task.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errOpenSubtasks.Error()})
+			return
+		}
+		if err := setTaskStatus(&task, status); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if updates.EstimatedHours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estimated hours cannot be negative"})
+		return
+	}
+
+	if updates.Priority != "" && taskPriorityRank[updates.Priority] == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must be LOW, MEDIUM, HIGH or URGENT"})
+		return
+	}
+
+	projectID := task.ProjectID
+	if updates.ProjectID != 0 && updates.ProjectID != task.ProjectID {
+		if !hasProjectRole(c, updates.ProjectID, models.ProjectRoleMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to move the task to this project"})
+			return
+		}
+		if countSubtasks(task.ID) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tasks with subtasks cannot move to another project"})
+			return
+		}
+		projectID = updates.ProjectID
+	}
+
+	// parent_id 0 detaches a subtask from its parent
+	oldParentID := task.ParentID
+	parentChanged := false
+	if updates.ParentID != nil {
+		if *updates.ParentID == 0 {
+			parentChanged = task.ParentID != nil
+		} else {
+			if err := validateTaskParent(task.ID, *updates.ParentID, projectID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			parentChanged = task.ParentID == nil || *task.ParentID != *updates.ParentID
+		}
+	} else if projectID != task.ProjectID && task.ParentID != nil {
+		// A subtask moved to another project leaves its parent behind
+		parentChanged = true
+		zero := uint(0)
+		updates.ParentID = &zero
+	}
+	newParentID := updates.ParentID
+	updates.ParentID = nil
+
+	if updates.MilestoneID != nil && !milestoneInProject(*updates.MilestoneID, projectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Milestone does not belong to the project"})
+		return
+	}
+
+	if updates.CustomFields != nil {
+		var err error
+		updates.CustomFields, err = validateCustomFields(c, models.CustomFieldEntityTask, updates.CustomFields, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	originalProjectID := task.ProjectID
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockTaskPositions(tx, originalProjectID, projectID); err != nil {
+			return err
+		}
+		if err := saveTaskStatus(tx, &task, utils.GetUserID(c)); err != nil {
+			return err
+		}
+		if err := tx.Model(&task).Updates(updates).Error; err != nil {
+			return err
+		}
+		if clearDueDate {
+			if err := tx.Model(&task).Update("due_date", nil).Error; err != nil {
+				return err
+			}
+			task.DueDate = nil
+		}
+		if updates.ProjectID != 0 && updates.ProjectID != originalProjectID {
+			if err := tx.Model(&task).Update("position", nextTaskPosition(tx, updates.ProjectID, task.Status)).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Attachment{}).Where("task_id = ?", task.ID).Update("project_id", updates.ProjectID).Error; err != nil {
+				return err
+			}
+		}
+		if tags != nil {
+			if err := tx.Model(&task).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+		if parentChanged {
+			if *newParentID == 0 {
+				newParentID = nil
+			}
+			if err := tx.Model(&task).Update("parent_id", newParentID).Error; err != nil {
+				return err
+			}
+			task.ParentID = newParentID
+			if err := syncParentStatus(tx, oldParentID); err != nil {
+				return err
+			}
+		}
+		if completing {
+			next, err := spawnNextInstance(tx, task.ID)
+			if err != nil {
+				return err
+			}
+			if next != nil {
+				task.NextInstanceID = &next.ID
+			}
+		}
+		return syncParentStatus(tx, task.ParentID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating task"})
 		return
 	}
 
+	if completing {
+		task.UnblockedTasks, err = unblockedBy(task.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching unblocked tasks"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, task)
 }
 
@@ -86,16 +400,344 @@ func DeleteTask(c *gin.Context) {
 	taskID := c.Param("id")
 	userID := utils.GetUserID(c)
 
-	result := database.DB.Where("id = ? AND user_id = ?", taskID, userID).Delete(&models.Task{})
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting task"})
+	var task models.Task
+	if err := database.DB.Where("id = ? AND project_id IN (?)", taskID, memberProjectIDs(c, models.ProjectRoleViewer)).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	// Members may delete their own tasks, managers any task.
+	if !hasProjectRole(c, task.ProjectID, models.ProjectRoleManager) &&
+		!(task.UserID == userID && hasProjectRole(c, task.ProjectID, models.ProjectRoleMember)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to delete this task"})
+		return
+	}
+
+	// Subtasks move up to the deleted task's parent
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("parent_id = ?", task.ID).Update("parent_id", task.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id = ?", task.ID).Delete(&models.TaskChecklistItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("task_id = ? OR blocked_by_id = ?", task.ID, task.ID).Delete(&models.TaskDependency{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id = ?", task.ID).Delete(&models.TaskComment{}).Error; err != nil {
+			return err
+		}
+		// Files stay with the project
+		if err := tx.Model(&models.Attachment{}).Where("task_id = ?", task.ID).Update("task_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id = ?", task.ID).Delete(&models.TaskStatusChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&task).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Delete(&task).Error; err != nil {
+			return err
+		}
+		return syncParentStatus(tx, task.ParentID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting task"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
+
+func milestoneInProject(milestoneID, projectID uint) bool {
+	var count int64
+	database.DB.Model(&models.Milestone{}).
+		Where("id = ? AND project_id = ?", milestoneID, projectID).
+		Count(&count)
+	return count > 0
+}
+
+// loadTaskDetails fills in the computed fields of tasks: tracked hours,
+// subtask and checklist progress, and whether the task is blocked.
+func loadTaskDetails(tasks []models.Task) error {
+	if err := loadTaskHours(tasks); err != nil {
+		return err
+	}
+	if err := loadTaskProgress(tasks); err != nil {
+		return err
+	}
+	return loadTaskBlocked(tasks)
+}
+
+// loadTaskHours fills in TrackedHours from everyone's time entries on the task
+// and its subtasks, and flags tasks whose tracked time exceeds their estimate.
+func loadTaskHours(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	hours, err := trackedHoursByTask(ids)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].TrackedHours = hours[tasks[i].ID]
+		tasks[i].OverEstimate = tasks[i].EstimatedHours > 0 && tasks[i].TrackedHours > tasks[i].EstimatedHours
+	}
+	return nil
+}
+
+func trackedHoursByTask(taskIDs []uint) (map[uint]float64, error) {
+	var rows []struct {
+		TaskID  uint
+		Seconds int64
+	}
+	err := database.DB.Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id AS root_id, id FROM tasks WHERE id IN ? AND deleted_at IS NULL
+			UNION
+			SELECT tree.root_id, t.id FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.deleted_at IS NULL
+		)
+		SELECT tree.root_id AS task_id, COALESCE(SUM(e.duration), 0) AS seconds
+		FROM tree JOIN time_entries e ON e.task_id = tree.id AND e.deleted_at IS NULL
+		GROUP BY tree.root_id`, taskIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hours := make(map[uint]float64)
+	for _, row := range rows {
+		hours[row.TaskID] = float64(row.Seconds) / 3600
+	}
+	return hours, nil
+}
+
+// loadTaskProgress counts each task's direct subtasks and checklist items and
+// how many of them are done.
+func loadTaskProgress(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	var subtasks []struct {
+		ParentID uint
+		Total    int
+		Done     int
+	}
+	err := database.DB.Model(&models.Task{}).
+		Select("parent_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE status = ?) AS done", models.TaskStatusCompleted).
+		Where("parent_id IN ?", ids).
+		Group("parent_id").
+		Scan(&subtasks).Error
+	if err != nil {
+		return err
+	}
+
+	var items []struct {
+		TaskID uint
+		Total  int
+		Done   int
+	}
+	err = database.DB.Model(&models.TaskChecklistItem{}).
+		Select("task_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE done) AS done").
+		Where("task_id IN ?", ids).
+		Group("task_id").
+		Scan(&items).Error
+	if err != nil {
+		return err
+	}
+
+	index := make(map[uint]int)
+	for i, t := range tasks {
+		index[t.ID] = i
+	}
+	for _, row := range subtasks {
+		tasks[index[row.ParentID]].SubtaskCount = row.Total
+		tasks[index[row.ParentID]].SubtasksDone = row.Done
+	}
+	for _, row := range items {
+		tasks[index[row.TaskID]].ChecklistCount = row.Total
+		tasks[index[row.TaskID]].ChecklistDone = row.Done
+	}
+	return nil
+}
+
+// validateTaskParent checks that parentID is a task in the project and not the
+// task itself or one of its subtasks.
+func validateTaskParent(taskID, parentID, projectID uint) error {
+	if !taskInProject(parentID, projectID) {
+		return errors.New("Parent task does not belong to the project")
+	}
+
+	var subtree []uint
+	err := database.DB.Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM tasks WHERE id = ? AND deleted_at IS NULL
+			UNION
+			SELECT t.id FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.deleted_at IS NULL
+		)
+		SELECT id FROM tree`, taskID).Scan(&subtree).Error
+	if err != nil {
+		return err
+	}
+	for _, id := range subtree {
+		if id == parentID {
+			return errors.New("A task cannot be moved under itself or one of its subtasks")
+		}
+	}
+	return nil
+}
+
+func countSubtasks(taskID uint) int64 {
+	var count int64
+	database.DB.Model(&models.Task{}).Where("parent_id = ?", taskID).Count(&count)
+	return count
+}
+
+var taskPriorityRank = map[string]int{
+	models.TaskPriorityLow:    1,
+	models.TaskPriorityMedium: 2,
+	models.TaskPriorityHigh:   3,
+	models.TaskPriorityUrgent: 4,
+}
+
+// applyTaskScheduleFilters applies the due date and priority filters of
+// GetTasks.
+func applyTaskScheduleFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if v := c.Query("priority"); v != "" {
+		priorities := strings.Split(strings.ToUpper(v), ",")
+		for _, p := range priorities {
+			if taskPriorityRank[p] == 0 {
+				return nil, fmt.Errorf("Invalid priority %q", p)
+			}
+		}
+		query = query.Where("priority IN ?", priorities)
+	}
+	if v := c.Query("due_before"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, errors.New("Invalid due_before date format")
+		}
+		query = query.Where("due_date < ?", d.AddDate(0, 0, 1))
+	}
+	if v := c.Query("due_after"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, errors.New("Invalid due_after date format")
+		}
+		query = query.Where("due_date >= ?", d)
+	}
+	if c.Query("overdue") == "true" {
+		query = query.Where("due_date < ? AND status <> ?", today(), models.TaskStatusCompleted)
+	}
+
+	return query, nil
+}
+
+// applyTaskContentFilters applies the status, text and logged time filters
+// of GetTasks.
+func applyTaskContentFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if v := c.Query("status"); v != "" {
+		statuses := strings.Split(strings.ToUpper(v), ",")
+		for _, s := range statuses {
+			if _, ok := taskTransitions[s]; !ok {
+				return nil, fmt.Errorf("Invalid status %q", s)
+			}
+		}
+		query = query.Where("status IN ?", statuses)
+	}
+	if v := strings.TrimSpace(c.Query("q")); v != "" {
+		pattern := "%" + likeEscaper.Replace(v) + "%"
+		query = query.Where("(title ILIKE ? OR description ILIKE ?)", pattern, pattern)
+	}
+	switch c.Query("has_time") {
+	case "":
+	case "true":
+		query = query.Where("EXISTS (?)", tasksWithTime())
+	case "false":
+		query = query.Where("NOT EXISTS (?)", tasksWithTime())
+	default:
+		return nil, errors.New("has_time must be true or false")
+	}
+	return query, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// tasksWithTime is a correlated subquery finding time logged on tasks.id.
+func tasksWithTime() *gorm.DB {
+	return database.DB.Model(&models.TimeEntry{}).Select("1").Where("time_entries.task_id = tasks.id")
+}
+
+// priorityOrder is an SQL expression ranking task priorities from low to urgent.
+func priorityOrder() string {
+	expr := "CASE priority"
+	for priority, rank := range taskPriorityRank {
+		expr += " WHEN '" + priority + "' THEN " + strconv.Itoa(rank)
+	}
+	return expr + " ELSE 0 END"
+}
+
+// today is the start of the current day in UTC, the day a task due today is
+// stored at. Tasks are overdue from the day after their due date.
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+type DueTasksResponse struct {
+	Overdue []models.Task `json:"overdue"`
+	DueSoon []models.Task `json:"due_soon"`
+}
+
+// GetDueTasks lists the open tasks across the caller's projects that are past
+// their due date or due within ?days= (default 7) days from today, most urgent
+// first.
+func GetDueTasks(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days cannot be negative"})
+		return
+	}
+
+	start := today()
+	var tasks []models.Task
+	err = database.DB.Where("project_id IN (?) AND status <> ?", memberProjectIDs(c, models.ProjectRoleViewer), models.TaskStatusCompleted).
+		Where("due_date < ?", start.AddDate(0, 0, days+1)).
+		Order("due_date").
+		Order(priorityOrder() + " DESC").
+		Preload("Tags").
+		Find(&tasks).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+
+	if err := loadTaskDetails(tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+
+	response := DueTasksResponse{Overdue: []models.Task{}, DueSoon: []models.Task{}}
+	for _, task := range tasks {
+		if task.DueDate.Before(start) {
+			response.Overdue = append(response.Overdue, task)
+		} else {
+			response.DueSoon = append(response.DueSoon, task)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
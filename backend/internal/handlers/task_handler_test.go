@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+)
+
+func TestUpdateTaskDueDate(t *testing.T) {
+	user, project := testProject(t)
+
+	due := time.Now().AddDate(0, 0, 3)
+	task := models.Task{
+		Title:       "Due soon",
+		Status:      models.TaskStatusTodo,
+		Priority:    models.TaskPriorityMedium,
+		DueDate:     &due,
+		ProjectID:   project.ID,
+		WorkspaceID: project.WorkspaceID,
+		UserID:      user.ID,
+	}
+	if err := database.DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(body string) models.Task {
+		t.Helper()
+		c, w := testContext(user, http.MethodPut, fmt.Sprintf("/tasks/%d", task.ID), strings.NewReader(body), "id", fmt.Sprint(task.ID))
+		UpdateTask(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("UpdateTask(%s): %d %s", body, w.Code, w.Body)
+		}
+		var updated models.Task
+		if err := database.DB.First(&updated, task.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return updated
+	}
+
+	if updated := update(`{"title": "Renamed"}`); updated.DueDate == nil {
+		t.Error("due date cleared by an update without due_date")
+	}
+	if updated := update(`{"due_date": null}`); updated.DueDate != nil {
+		t.Errorf("due date = %v after setting it to null", updated.DueDate)
+	}
+	if updated := update(`{"due_date": "2030-01-02T00:00:00Z"}`); updated.DueDate == nil || updated.DueDate.Year() != 2030 {
+		t.Errorf("due date = %v, want 2030-01-02", updated.DueDate)
+	}
+}
+
+func TestGetDueTasksDays(t *testing.T) {
+	user, _ := testProject(t)
+
+	for days, want := range map[string]int{"0": http.StatusOK, "7": http.StatusOK, "-1": http.StatusBadRequest, "soon": http.StatusBadRequest} {
+		c, w := testContext(user, http.MethodGet, "/tasks/due?days="+days, nil)
+		GetDueTasks(c)
+		if w.Code != want {
+			t.Errorf("GetDueTasks(days=%s): %d, want %d", days, w.Code, want)
+		}
+	}
+}
+
+func TestGetDueTasksToday(t *testing.T) {
+	user, project := testProject(t)
+
+	for title, due := range map[string]time.Time{
+		"Yesterday": today().AddDate(0, 0, -1),
+		"Today":     today(),
+		"In a week": today().AddDate(0, 0, 7),
+		"Later":     today().AddDate(0, 0, 8),
+	} {
+		task := models.Task{Title: title, Status: models.TaskStatusTodo, DueDate: &due, ProjectID: project.ID, WorkspaceID: project.WorkspaceID, UserID: user.ID}
+		if err := database.DB.Create(&task).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, w := testContext(user, http.MethodGet, "/tasks/due", nil)
+	GetDueTasks(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetDueTasks: %d %s", w.Code, w.Body)
+	}
+	var response DueTasksResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	titles := func(tasks []models.Task) []string {
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+	if got := titles(response.Overdue); !reflect.DeepEqual(got, []string{"Yesterday"}) {
+		t.Errorf("overdue = %v, want [Yesterday]", got)
+	}
+	if got := titles(response.DueSoon); !reflect.DeepEqual(got, []string{"Today", "In a week"}) {
+		t.Errorf("due soon = %v, want [Today In a week]", got)
+	}
+}
+
+func TestCreateTaskTimeEntries(t *testing.T) {
+	user, project := testProject(t)
+
+	body := fmt.Sprintf(`{"title": "Injected", "project_id": %d,
+		"time_entries": [{"start_time": "2024-01-01T09:00:00Z", "end_time": "2024-01-01T17:00:00Z", "duration": 28800, "project_id": %d}]}`, project.ID, project.ID)
+	c, w := testContext(user, http.MethodPost, "/tasks", strings.NewReader(body))
+	CreateTask(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateTask: %d %s", w.Code, w.Body)
+	}
+
+	var count int64
+	database.DB.Model(&models.TimeEntry{}).Where("project_id = ?", project.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("%d time entries created through the request body", count)
+	}
+}
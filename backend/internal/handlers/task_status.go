@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// taskTransitions lists the statuses a task may move to from each status.
+var taskTransitions = map[string][]string{
+	models.TaskStatusTodo:       {models.TaskStatusInProgress, models.TaskStatusOnHold, models.TaskStatusCompleted},
+	models.TaskStatusInProgress: {models.TaskStatusTodo, models.TaskStatusOnHold, models.TaskStatusCompleted},
+	models.TaskStatusOnHold:     {models.TaskStatusTodo, models.TaskStatusInProgress},
+	models.TaskStatusCompleted:  {models.TaskStatusTodo, models.TaskStatusInProgress},
+}
+
+// setTaskStatus moves the task to status, checking the transition and
+// recording when work started and when the task was completed. Reopening a
+// completed task clears its completion time.
+func setTaskStatus(task *models.Task, status string) error {
+	if _, ok := taskTransitions[status]; !ok {
+		return fmt.Errorf("status must be one of TODO, IN_PROGRESS, ON_HOLD or COMPLETED")
+	}
+	if task.Status == status {
+		return nil
+	}
+
+	if task.Status != "" {
+		allowed := false
+		for _, next := range taskTransitions[task.Status] {
+			if next == status {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("cannot change status from %s to %s", task.Status, status)
+		}
+	}
+
+	now := time.Now()
+	switch status {
+	case models.TaskStatusInProgress:
+		if task.StartedAt == nil {
+			task.StartedAt = &now
+		}
+		task.CompletedAt = nil
+	case models.TaskStatusCompleted:
+		if task.StartedAt == nil {
+			task.StartedAt = &now
+		}
+		task.CompletedAt = &now
+	default:
+		task.CompletedAt = nil
+	}
+	task.Status = status
+
+	return nil
+}
+
+// saveTaskStatus persists the fields set by setTaskStatus and records the
+// change in the task's history on behalf of userID, 0 for changes derived from
+// subtasks. A task that changes status moves to the end of its new board
+// column.
+func saveTaskStatus(tx *gorm.DB, task *models.Task, userID uint) error {
+	if err := lockTaskPositions(tx, task.ProjectID); err != nil {
+		return err
+	}
+
+	var changedBy *uint
+	if userID != 0 {
+		changedBy = &userID
+	}
+	err := tx.Exec(`
+		INSERT INTO task_status_changes (created_at, updated_at, task_id, from_status, to_status, user_id)
+		SELECT now(), now(), id, status, ?, ? FROM tasks WHERE id = ? AND status <> ?`,
+		task.Status, changedBy, task.ID, task.Status).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(task).Updates(map[string]interface{}{
+		"status":       task.Status,
+		"started_at":   task.StartedAt,
+		"completed_at": task.CompletedAt,
+		"position": gorm.Expr("CASE WHEN status = ? THEN position ELSE ? END",
+			task.Status, nextTaskPosition(tx, task.ProjectID, task.Status)),
+	}).Error
+}
+
+// lockTaskPositions locks the rows of the projects until the transaction
+// ends, in id order. Everything that assigns board positions in a project
+// takes this lock first, so concurrent changes never hand out the same
+// position twice.
+func lockTaskPositions(tx *gorm.DB, projectIDs ...uint) error {
+	var projects []models.Project
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id IN ?", projectIDs).
+		Order("id").
+		Find(&projects).Error
+}
+
+// nextTaskPosition is a subquery for the position after the last task of a
+// board column. Callers must hold lockTaskPositions.
+func nextTaskPosition(tx *gorm.DB, projectID uint, status string) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.Task{}).
+		Select("COALESCE(MAX(position), -1) + 1").
+		Where("project_id = ? AND status = ?", projectID, status)
+}
+
+// startTaskForTimer moves a TODO task to IN_PROGRESS when userID logs time on
+// it.
+func startTaskForTimer(tx *gorm.DB, taskID, userID uint) error {
+	if taskID == 0 {
+		return nil
+	}
+
+	var task models.Task
+	if err := tx.First(&task, taskID).Error; err != nil {
+		return err
+	}
+	if task.Status != models.TaskStatusTodo {
+		return nil
+	}
+	if err := setTaskStatus(&task, models.TaskStatusInProgress); err != nil {
+		return err
+	}
+	if err := saveTaskStatus(tx, &task, userID); err != nil {
+		return err
+	}
+	return syncParentStatus(tx, task.ParentID)
+}
+
+var errOpenSubtasks = errors.New("Task has open subtasks or checklist items")
+
+// hasOpenSubtasks reports whether the task has subtasks that are not completed
+// or checklist items that are not done.
+func hasOpenSubtasks(taskID uint) bool {
+	var open int64
+	database.DB.Model(&models.Task{}).
+		Where("parent_id = ? AND status <> ?", taskID, models.TaskStatusCompleted).
+		Count(&open)
+	if open > 0 {
+		return true
+	}
+	database.DB.Model(&models.TaskChecklistItem{}).
+		Where("task_id = ? AND NOT done", taskID).
+		Count(&open)
+	return open > 0
+}
+
+// syncParentStatus derives a parent task's status from its subtasks and
+// checklist items, and walks on up the tree. A parent is completed once
+// everything under it is done, reopened when something new is open, and in
+// progress as soon as any part of it is. Transitions the state machine does
+// not allow, such as out of ON_HOLD, are left alone. A recurring parent that
+// completes spawns its next instance.
+func syncParentStatus(tx *gorm.DB, parentID *uint) error {
+	for parentID != nil {
+		var parent models.Task
+		err := tx.First(&parent, *parentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var counts struct {
+			Total   int
+			Done    int
+			Started int
+		}
+		err = tx.Raw(`
+			SELECT COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE done) AS done,
+				COUNT(*) FILTER (WHERE started) AS started
+			FROM (
+				SELECT status = ? AS done, status <> ? AS started FROM tasks WHERE parent_id = ? AND deleted_at IS NULL
+				UNION ALL
+				SELECT done, done FROM task_checklist_items WHERE task_id = ? AND deleted_at IS NULL
+			) children`,
+			models.TaskStatusCompleted, models.TaskStatusTodo, parent.ID, parent.ID).
+			Scan(&counts).Error
+		if err != nil {
+			return err
+		}
+		if counts.Total == 0 {
+			return nil
+		}
+
+		status := parent.Status
+		switch {
+		case counts.Done == counts.Total:
+			status = models.TaskStatusCompleted
+		case parent.Status == models.TaskStatusCompleted:
+			status = models.TaskStatusInProgress
+		case parent.Status == models.TaskStatusTodo && counts.Started > 0:
+			status = models.TaskStatusInProgress
+		}
+		if status == parent.Status || setTaskStatus(&parent, status) != nil {
+			return nil
+		}
+		if err := saveTaskStatus(tx, &parent, 0); err != nil {
+			return err
+		}
+		if parent.Status == models.TaskStatusCompleted {
+			if _, err := spawnNextInstance(tx, parent.ID); err != nil {
+				return err
+			}
+		}
+
+		parentID = parent.ParentID
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+)
+
+// TestTaskPositionsConcurrent creates tasks and moves them between columns
+// in parallel, and checks no two tasks of a board column share a position.
+func TestTaskPositionsConcurrent(t *testing.T) {
+	user, project := testProject(t)
+	const n = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"title": "Task %d", "project_id": %d}`, i, project.ID)
+			c, w := testContext(user, http.MethodPost, "/tasks", strings.NewReader(body))
+			CreateTask(c)
+			if w.Code != http.StatusCreated {
+				t.Errorf("CreateTask: %d %s", w.Code, w.Body)
+			}
+		}(i)
+	}
+	wg.Wait()
+	checkTaskPositions(t, project.ID)
+
+	var ids []uint
+	if err := database.DB.Model(&models.Task{}).Where("project_id = ?", project.ID).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != n {
+		t.Fatalf("created %d tasks, want %d", len(ids), n)
+	}
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id uint) {
+			defer wg.Done()
+			status := models.TaskStatusInProgress
+			if i%3 == 0 {
+				status = models.TaskStatusOnHold
+			}
+			body := fmt.Sprintf(`{"status": %q}`, status)
+			c, w := testContext(user, http.MethodPut, "/tasks", strings.NewReader(body), "id", fmt.Sprint(id))
+			UpdateTask(c)
+			if w.Code != http.StatusOK {
+				t.Errorf("UpdateTask: %d %s", w.Code, w.Body)
+			}
+		}(i, id)
+
+		if i%2 == 0 {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				body := fmt.Sprintf(`{"status": %q, "position": 0}`, models.TaskStatusInProgress)
+				c, w := testContext(user, http.MethodPut, "/tasks", strings.NewReader(body), "id", fmt.Sprint(ids[n-1-i]))
+				MoveTask(c)
+				if w.Code != http.StatusOK {
+					t.Errorf("MoveTask: %d %s", w.Code, w.Body)
+				}
+			}(i)
+		}
+	}
+	wg.Wait()
+	checkTaskPositions(t, project.ID)
+}
+
+func checkTaskPositions(t *testing.T, projectID uint) {
+	t.Helper()
+
+	var tasks []models.Task
+	if err := database.DB.Where("project_id = ?", projectID).Find(&tasks).Error; err != nil {
+		t.Fatal(err)
+	}
+	taken := make(map[string]uint)
+	for _, task := range tasks {
+		key := fmt.Sprintf("%s/%d", task.Status, task.Position)
+		if other, ok := taken[key]; ok {
+			t.Fatalf("tasks %d and %d are both at %s", other, task.ID, key)
+		}
+		taken[key] = task.ID
+	}
+}
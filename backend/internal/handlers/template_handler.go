@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SaveTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CreateFromTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var templateSorts = listSorts{"name": "LOWER(name)", "created_at": "created_at"}
+
+func GetTemplates(c *gin.Context) {
+	list, err := parseListQuery(c, "project_templates", templateSorts, "created_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var templates []models.ProjectTemplate
+	query := database.DB.Preload("Tasks", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("Tasks.Tags").
+		Where("workspace_id = ?", utils.GetWorkspaceID(c))
+	if err := list.apply(query).Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching templates"})
+		return
+	}
+	templates = page(c, list, templates, func(t models.ProjectTemplate) uint { return t.ID })
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func GetTemplate(c *gin.Context) {
+	templateID := c.Param("id")
+
+	var template models.ProjectTemplate
+	if err := database.DB.Preload("Tasks", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("Tasks.Tags").
+		Where("id = ? AND workspace_id = ?", templateID, utils.GetWorkspaceID(c)).First(&template).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// SaveProjectAsTemplate stores a project's tasks, tags, rate and budget
+// settings as a reusable template.
+func SaveProjectAsTemplate(c *gin.Context) {
+	projectID := c.Param("id")
+	userID := utils.GetUserID(c)
+
+	var req SaveTemplateRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project models.Project
+	if err := database.DB.Preload("Tasks", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).Preload("Tasks.Tags").
+		Where("id = ? AND id IN (?)", projectID, memberProjectIDs(c, models.ProjectRoleManager)).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	template := models.ProjectTemplate{
+		Name:         req.Name,
+		Description:  req.Description,
+		HourlyRate:   project.HourlyRate,
+		Currency:     project.Currency,
+		BudgetHours:  project.BudgetHours,
+		BudgetAmount: project.BudgetAmount,
+		WorkspaceID:  project.WorkspaceID,
+		UserID:       userID,
+	}
+	if template.Name == "" {
+		template.Name = project.Name
+	}
+	if template.Description == "" {
+		template.Description = project.Description
+	}
+	for _, task := range project.Tasks {
+		template.Tasks = append(template.Tasks, models.ProjectTemplateTask{
+			Title:          task.Title,
+			Description:    task.Description,
+			Tags:           task.Tags,
+			EstimatedHours: task.EstimatedHours,
+		})
+	}
+
+	if err := database.DB.Create(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func CreateProjectFromTemplate(c *gin.Context) {
+	templateID := c.Param("id")
+	userID := utils.GetUserID(c)
+
+	var req CreateFromTemplateRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var template models.ProjectTemplate
+	if err := database.DB.Preload("Tasks", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("Tasks.Tags").
+		Where("id = ? AND workspace_id = ?", templateID, utils.GetWorkspaceID(c)).First(&template).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	project := models.Project{
+		Name:         req.Name,
+		Description:  req.Description,
+		HourlyRate:   template.HourlyRate,
+		Currency:     template.Currency,
+		BudgetHours:  template.BudgetHours,
+		BudgetAmount: template.BudgetAmount,
+		WorkspaceID:  template.WorkspaceID,
+		UserID:       userID,
+	}
+	if project.Name == "" {
+		project.Name = template.Name
+	}
+	if project.Description == "" {
+		project.Description = template.Description
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+		for i, templateTask := range template.Tasks {
+			task := models.Task{
+				Title:          templateTask.Title,
+				Description:    templateTask.Description,
+				Status:         models.TaskStatusTodo,
+				Tags:           templateTask.Tags,
+				EstimatedHours: templateTask.EstimatedHours,
+				Position:       i,
+				ProjectID:      project.ID,
+				WorkspaceID:    project.WorkspaceID,
+				UserID:         userID,
+			}
+			if err := tx.Create(&task).Error; err != nil {
+				return err
+			}
+			project.Tasks = append(project.Tasks, task)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+func DeleteTemplate(c *gin.Context) {
+	templateID := c.Param("id")
+
+	var template models.ProjectTemplate
+	if err := database.DB.Where("id = ? AND workspace_id = ?", templateID, utils.GetWorkspaceID(c)).First(&template).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	if template.UserID != utils.GetUserID(c) && !isWorkspaceAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to delete this template"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM project_template_task_tags WHERE project_template_task_id IN (?)",
+			tx.Model(&models.ProjectTemplateTask{}).Select("id").Where("template_id = ?", template.ID)).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", template.ID).Delete(&models.ProjectTemplateTask{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&template).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting template"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -2,12 +2,17 @@
 package handlers
 
 import (
+	"encoding/csv"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"timetracker/internal/database"
 	"timetracker/internal/models"
 	"timetracker/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func CreateTimeEntry(c *gin.Context) {
@@ -18,9 +23,41 @@ func CreateTimeEntry(c *gin.Context) {
 	}
 
 	entry.UserID = utils.GetUserID(c)
+	entry.WorkspaceID = utils.GetWorkspaceID(c)
 	entry.Duration = entry.EndTime.Unix() - entry.StartTime.Unix()
+	entry.Running = false
+	entry.WorklogID, entry.WorklogAt = "", nil
 
-	if err := database.DB.Create(&entry).Error; err != nil {
+	if !hasProjectRole(c, entry.ProjectID, models.ProjectRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to log time on this project"})
+		return
+	}
+
+	if entry.TaskID != 0 && !taskInProject(entry.TaskID, entry.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task does not belong to the project"})
+		return
+	}
+
+	var err error
+	entry.CustomFields, err = validateCustomFields(c, models.CustomFieldEntityTimeEntry, entry.CustomFields, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry.Tags, err = resolveTags(database.DB, c, entry.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return startTaskForTimer(tx, entry.TaskID, entry.UserID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating time entry"})
 		return
 	}
@@ -28,24 +65,223 @@ func CreateTimeEntry(c *gin.Context) {
 	c.JSON(http.StatusCreated, entry)
 }
 
-func GetTimeEntries(c *gin.Context) {
+// StartTimeEntry starts a running timer for the caller. Only one timer may run
+// at a time; a TODO task moves to IN_PROGRESS.
+func StartTimeEntry(c *gin.Context) {
+	var entry models.TimeEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	userID := utils.GetUserID(c)
-	var entries []models.TimeEntry
+	entry.UserID = userID
+	entry.WorkspaceID = utils.GetWorkspaceID(c)
+	entry.StartTime = time.Now()
+	entry.EndTime = time.Time{}
+	entry.Duration = 0
+	entry.Running = true
+	entry.WorklogID, entry.WorklogAt = "", nil
+
+	if !hasProjectRole(c, entry.ProjectID, models.ProjectRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to log time on this project"})
+		return
+	}
+
+	if entry.TaskID != 0 && !taskInProject(entry.TaskID, entry.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task does not belong to the project"})
+		return
+	}
+
+	var err error
+	entry.CustomFields, err = validateCustomFields(c, models.CustomFieldEntityTimeEntry, entry.CustomFields, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry.Tags, err = resolveTags(database.DB, c, entry.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var running int64
+	database.DB.Model(&models.TimeEntry{}).Where("user_id = ? AND running", userID).Count(&running)
+	if running > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A timer is already running"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return startTaskForTimer(tx, entry.TaskID, entry.UserID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting timer"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+func StopTimeEntry(c *gin.Context) {
+	id := c.Param("id")
+
+	var entry models.TimeEntry
+	if err := visibleTimeEntries(c).Where("id = ?", id).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+		return
+	}
+
+	if !canEditTimeEntry(c, entry) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to update this time entry"})
+		return
+	}
+
+	if !entry.Running {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Timer is not running"})
+		return
+	}
+
+	entry.EndTime = time.Now()
+	entry.Duration = entry.EndTime.Unix() - entry.StartTime.Unix()
+	entry.Running = false
+	entry.WorklogID, entry.WorklogAt = "", nil
+
+	if err := database.DB.Save(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error stopping timer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+var timeEntrySorts = listSorts{"start_time": "start_time", "duration": "duration", "created_at": "created_at"}
+
+// GetTimeEntries returns the caller's own entries, plus everyone's entries on
+// projects the caller owns or manages, newest first. Filter with ?project_id=
+// and ?user_id=.
+func GetTimeEntries(c *gin.Context) {
+	list, err := parseListQuery(c, "time_entries", timeEntrySorts, "start_time", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := database.DB.Where("user_id = ?", userID).Find(&entries).Error; err != nil {
+	var entries []models.TimeEntry
+	if err := list.apply(filteredTimeEntries(c)).Preload("Tags").Find(&entries).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching time entries"})
 		return
 	}
+	entries = page(c, list, entries, func(e models.TimeEntry) uint { return e.ID })
 
 	c.JSON(http.StatusOK, entries)
 }
 
+// filteredTimeEntries applies the list filters shared by GetTimeEntries and
+// ExportTimeEntries to the caller's visible entries.
+func filteredTimeEntries(c *gin.Context) *gorm.DB {
+	query := visibleTimeEntries(c)
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if memberID := c.Query("user_id"); memberID != "" {
+		query = query.Where("user_id = ?", memberID)
+	}
+	if running := c.Query("running"); running != "" {
+		query = query.Where("running = ?", running == "true")
+	}
+	query = applyTagFilter(c, query, "time_entries", "time_entry_tags", "time_entry_id")
+	return applyCustomFieldFilters(c, query, "time_entries")
+}
+
+// ExportTimeEntries writes the filtered entries as CSV, with one column per
+// time entry custom field followed by the project's custom fields.
+func ExportTimeEntries(c *gin.Context) {
+	var entries []models.TimeEntry
+	if err := filteredTimeEntries(c).Preload("Tags").Order("start_time").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching time entries"})
+		return
+	}
+
+	entryFields, err := loadCustomFields(utils.GetWorkspaceID(c), models.CustomFieldEntityTimeEntry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching custom fields"})
+		return
+	}
+	projectFields, err := loadCustomFields(utils.GetWorkspaceID(c), models.CustomFieldEntityProject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching custom fields"})
+		return
+	}
+
+	projectIDs := make([]uint, 0)
+	taskIDs := make([]uint, 0)
+	for _, entry := range entries {
+		projectIDs = append(projectIDs, entry.ProjectID)
+		if entry.TaskID != 0 {
+			taskIDs = append(taskIDs, entry.TaskID)
+		}
+	}
+
+	var projects []models.Project
+	database.DB.Where("id IN ?", projectIDs).Find(&projects)
+	projectsByID := make(map[uint]models.Project)
+	for _, p := range projects {
+		projectsByID[p.ID] = p
+	}
+
+	var tasks []models.Task
+	database.DB.Where("id IN ?", taskIDs).Find(&tasks)
+	tasksByID := make(map[uint]models.Task)
+	for _, t := range tasks {
+		tasksByID[t.ID] = t
+	}
+
+	header := []string{"Date", "Start", "End", "Hours", "Project", "Task", "Tags", "Notes"}
+	for _, f := range entryFields {
+		header = append(header, f.Label)
+	}
+	for _, f := range projectFields {
+		header = append(header, "Project: "+f.Label)
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="time-entries.csv"`)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(header)
+	for _, entry := range entries {
+		project := projectsByID[entry.ProjectID]
+		record := []string{
+			entry.StartTime.Format("2006-01-02"),
+			entry.StartTime.Format("15:04"),
+			entry.EndTime.Format("15:04"),
+			strconv.FormatFloat(float64(entry.Duration)/3600, 'f', 2, 64),
+			project.Name,
+			tasksByID[entry.TaskID].Title,
+			tagNames(entry.Tags),
+			entry.Notes,
+		}
+		for _, f := range entryFields {
+			record = append(record, formatCustomFieldValue(entry.CustomFields[f.Key]))
+		}
+		for _, f := range projectFields {
+			record = append(record, formatCustomFieldValue(project.CustomFields[f.Key]))
+		}
+		w.Write(record)
+	}
+	w.Flush()
+}
+
 func GetTimeEntry(c *gin.Context) {
 	id := c.Param("id")
-	userID := utils.GetUserID(c)
 	var entry models.TimeEntry
 
-	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&entry).Error; err != nil {
+	if err := visibleTimeEntries(c).Preload("Tags").Where("id = ?", id).First(&entry).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
 		return
 	}
@@ -54,16 +290,67 @@ func GetTimeEntry(c *gin.Context) {
 }
 
 func UpdateTimeEntry(c *gin.Context) {
+	id := c.Param("id")
+
+	var existing models.TimeEntry
+	if err := visibleTimeEntries(c).Where("id = ?", id).First(&existing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+		return
+	}
+
+	if !canEditTimeEntry(c, existing) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to update this time entry"})
+		return
+	}
+
 	var entry models.TimeEntry
 	if err := c.ShouldBindJSON(&entry); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	entry.UserID = utils.GetUserID(c)
+	entry.ID = existing.ID
+	entry.CreatedAt = existing.CreatedAt
+	entry.UserID = existing.UserID
+	entry.WorkspaceID = existing.WorkspaceID
+	entry.Running = existing.Running
+	entry.WorklogID, entry.WorklogAt = existing.WorklogID, existing.WorklogAt
 	entry.Duration = entry.EndTime.Unix() - entry.StartTime.Unix()
+	if entry.Running {
+		entry.EndTime = time.Time{}
+		entry.Duration = 0
+	}
 
-	if err := database.DB.Save(&entry).Error; err != nil {
+	if entry.ProjectID != existing.ProjectID && !hasProjectRole(c, entry.ProjectID, models.ProjectRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to log time on this project"})
+		return
+	}
+
+	if entry.TaskID != 0 && !taskInProject(entry.TaskID, entry.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task does not belong to the project"})
+		return
+	}
+
+	var err error
+	entry.CustomFields, err = validateCustomFields(c, models.CustomFieldEntityTimeEntry, entry.CustomFields, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry.Tags, err = resolveTags(database.DB, c, entry.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags").Save(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entry).Association("Tags").Replace(entry.Tags)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating time entry"})
 		return
 	}
@@ -73,9 +360,25 @@ func UpdateTimeEntry(c *gin.Context) {
 
 func DeleteTimeEntry(c *gin.Context) {
 	id := c.Param("id")
-	userID := utils.GetUserID(c)
 
-	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.TimeEntry{}).Error; err != nil {
+	var entry models.TimeEntry
+	if err := visibleTimeEntries(c).Where("id = ?", id).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+		return
+	}
+
+	if !canEditTimeEntry(c, entry) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to delete this time entry"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entry).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&entry).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting time entry"})
 		return
 	}
@@ -83,4 +386,30 @@ func DeleteTimeEntry(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// canEditTimeEntry lets members change their own entries and managers change
+// anyone's.
+func canEditTimeEntry(c *gin.Context, entry models.TimeEntry) bool {
+	if entry.UserID == utils.GetUserID(c) {
+		return hasProjectRole(c, entry.ProjectID, models.ProjectRoleMember)
+	}
+	return hasProjectRole(c, entry.ProjectID, models.ProjectRoleManager)
+}
+
+func taskInProject(taskID, projectID uint) bool {
+	var count int64
+	database.DB.Model(&models.Task{}).
+		Where("id = ? AND project_id = ?", taskID, projectID).
+		Count(&count)
+	return count > 0
+}
+
 // Continue with other time entry handlers...
+
+// tagNames joins tag names for exports.
+func tagNames(tags []models.Tag) string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type WorkspaceMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type WorkspaceResponse struct {
+	models.Workspace
+	Role   string `json:"role"`
+	Active bool   `json:"active"`
+}
+
+var workspaceSorts = listSorts{"name": "LOWER(workspaces.name)", "created_at": "workspaces.created_at"}
+
+// workspaceMemberSorts also serves the project member list, hence the
+// unqualified columns.
+var workspaceMemberSorts = listSorts{
+	"email":      "(SELECT LOWER(users.email) FROM users WHERE users.id = user_id)",
+	"created_at": "created_at",
+}
+
+func GetWorkspaces(c *gin.Context) {
+	list, err := parseListQuery(c, "workspaces", workspaceSorts, "name", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := database.DB.Model(&models.Workspace{}).
+		Select("workspaces.*, workspace_members.role").
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id AND workspace_members.deleted_at IS NULL").
+		Where("workspace_members.user_id = ?", utils.GetUserID(c))
+
+	workspaces := []WorkspaceResponse{}
+	if err := list.apply(query).Scan(&workspaces).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching workspaces"})
+		return
+	}
+	workspaces = page(c, list, workspaces, func(w WorkspaceResponse) uint { return w.ID })
+
+	activeID := utils.GetWorkspaceID(c)
+	for i := range workspaces {
+		workspaces[i].Active = workspaces[i].ID == activeID
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+func GetWorkspace(c *gin.Context) {
+	member, ok := findWorkspaceMembership(c, models.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, WorkspaceResponse{
+		Workspace: member.Workspace,
+		Role:      member.Role,
+		Active:    member.WorkspaceID == utils.GetWorkspaceID(c),
+	})
+}
+
+func CreateWorkspace(c *gin.Context) {
+	var req WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace := models.Workspace{
+		Name:    req.Name,
+		OwnerID: utils.GetUserID(c),
+	}
+	if err := database.DB.Create(&workspace).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating workspace"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, WorkspaceResponse{Workspace: workspace, Role: models.WorkspaceRoleOwner})
+}
+
+func UpdateWorkspace(c *gin.Context) {
+	member, ok := findWorkspaceMembership(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace := member.Workspace
+	if err := database.DB.Model(&workspace).Update("name", req.Name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating workspace"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WorkspaceResponse{Workspace: workspace, Role: member.Role})
+}
+
+// DeleteWorkspace removes an empty shared workspace. Personal workspaces and
+// workspaces that still hold projects cannot be deleted.
+func DeleteWorkspace(c *gin.Context) {
+	member, ok := findWorkspaceMembership(c, models.WorkspaceRoleOwner)
+	if !ok {
+		return
+	}
+
+	if member.Workspace.Personal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Personal workspaces cannot be deleted"})
+		return
+	}
+
+	var projects int64
+	database.DB.Model(&models.Project{}).Where("workspace_id = ?", member.WorkspaceID).Count(&projects)
+	if projects > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Workspace still has projects"})
+		return
+	}
+
+	if err := database.DB.Unscoped().Where("workspace_id = ?", member.WorkspaceID).Delete(&models.WorkspaceMember{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting workspace"})
+		return
+	}
+	if err := database.DB.Delete(&member.Workspace).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting workspace"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SwitchWorkspace makes the workspace the default for requests that do not
+// send an X-Workspace-ID header.
+func SwitchWorkspace(c *gin.Context) {
+	member, ok := findWorkspaceMembership(c, models.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", member.UserID).Update("active_workspace_id", member.WorkspaceID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error switching workspace"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WorkspaceResponse{Workspace: member.Workspace, Role: member.Role, Active: true})
+}
+
+func GetWorkspaceMembers(c *gin.Context) {
+	member, ok := findWorkspaceMembership(c, models.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	list, err := parseListQuery(c, "workspace_members", workspaceMemberSorts, "created_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var members []models.WorkspaceMember
+	if err := list.apply(database.DB.Preload("User").Where("workspace_id = ?", member.WorkspaceID)).Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching members"})
+		return
+	}
+	members = page(c, list, members, func(m models.WorkspaceMember) uint { return m.ID })
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddWorkspaceMember adds an existing user, looked up by email, to a shared
+// workspace.
+func AddWorkspaceMember(c *gin.Context) {
+	admin, ok := findWorkspaceMembership(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req WorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !canAssignWorkspaceRole(admin.Role, req.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to assign this role"})
+		return
+	}
+	if admin.Workspace.Personal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Personal workspaces cannot be shared"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(req.Email))).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.WorkspaceMember{}).Where("workspace_id = ? AND user_id = ?", admin.WorkspaceID, user.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+		return
+	}
+
+	member := models.WorkspaceMember{
+		WorkspaceID: admin.WorkspaceID,
+		UserID:      user.ID,
+		Role:        req.Role,
+	}
+	if err := database.DB.Create(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error adding member"})
+		return
+	}
+
+	member.User = user
+	c.JSON(http.StatusCreated, member)
+}
+
+func UpdateWorkspaceMember(c *gin.Context) {
+	admin, ok := findWorkspaceMembership(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req MemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var member models.WorkspaceMember
+	if err := database.DB.Where("workspace_id = ? AND user_id = ?", admin.WorkspaceID, c.Param("userId")).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if !canAssignWorkspaceRole(admin.Role, member.Role) || !canAssignWorkspaceRole(admin.Role, req.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to assign this role"})
+		return
+	}
+
+	if member.Role == models.WorkspaceRoleOwner && req.Role != models.WorkspaceRoleOwner && countWorkspaceOwners(member.WorkspaceID) <= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A workspace needs at least one owner"})
+		return
+	}
+
+	member.Role = req.Role
+	if err := database.DB.Save(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveWorkspaceMember removes a member and their project memberships in the
+// workspace. Members may always remove themselves.
+func RemoveWorkspaceMember(c *gin.Context) {
+	caller, ok := findWorkspaceMembership(c, models.WorkspaceRoleMember)
+	if !ok {
+		return
+	}
+
+	var member models.WorkspaceMember
+	if err := database.DB.Where("workspace_id = ? AND user_id = ?", caller.WorkspaceID, c.Param("userId")).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if member.UserID != caller.UserID && !canAssignWorkspaceRole(caller.Role, member.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to remove this member"})
+		return
+	}
+
+	if member.Role == models.WorkspaceRoleOwner && countWorkspaceOwners(member.WorkspaceID) <= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A workspace needs at least one owner"})
+		return
+	}
+
+	projects := database.DB.Model(&models.Project{}).Select("id").Where("workspace_id = ?", member.WorkspaceID)
+	if err := database.DB.Unscoped().Where("user_id = ? AND project_id IN (?)", member.UserID, projects).Delete(&models.ProjectMember{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error removing member"})
+		return
+	}
+	if err := database.DB.Unscoped().Delete(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error removing member"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findWorkspaceMembership loads the caller's membership of the workspace in
+// the URL and checks it is at least minRole, writing the error response when
+// it is not.
+func findWorkspaceMembership(c *gin.Context, minRole string) (models.WorkspaceMember, bool) {
+	var member models.WorkspaceMember
+	err := database.DB.Preload("Workspace").
+		Where("workspace_id = ? AND user_id = ?", c.Param("id"), utils.GetUserID(c)).
+		First(&member).Error
+	if err != nil || member.Workspace.ID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return member, false
+	}
+
+	if workspaceRoleRank[member.Role] < workspaceRoleRank[minRole] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage this workspace"})
+		return member, false
+	}
+
+	return member, true
+}
+
+// canAssignWorkspaceRole reports whether a member with callerRole may grant or
+// revoke role: owners manage every role, admins only members.
+func canAssignWorkspaceRole(callerRole, role string) bool {
+	if workspaceRoleRank[role] == 0 {
+		return false
+	}
+	switch callerRole {
+	case models.WorkspaceRoleOwner:
+		return true
+	case models.WorkspaceRoleAdmin:
+		return role == models.WorkspaceRoleMember
+	default:
+		return false
+	}
+}
+
+func countWorkspaceOwners(workspaceID uint) int64 {
+	var count int64
+	database.DB.Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND role = ?", workspaceID, models.WorkspaceRoleOwner).
+		Count(&count)
+	return count
+}
+
+// ensureWorkspaceMember adds the user to the workspace as a plain member when
+// they do not belong to it yet.
+func ensureWorkspaceMember(tx *gorm.DB, workspaceID, userID uint) error {
+	var count int64
+	tx.Model(&models.WorkspaceMember{}).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	return tx.Create(&models.WorkspaceMember{
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Role:        models.WorkspaceRoleMember,
+	}).Error
+}
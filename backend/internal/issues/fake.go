@@ -0,0 +1,58 @@
+package issues
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fake is an in-memory Provider for tests. Issues are added with Set; logged
+// worklogs can be read back from Worklogs.
+type Fake struct {
+	mu       sync.Mutex
+	issues   map[string]Issue
+	worklogs map[string][]Worklog
+	nextID   int
+}
+
+func NewFake() *Fake {
+	return &Fake{issues: make(map[string]Issue), worklogs: make(map[string][]Worklog)}
+}
+
+func (f *Fake) Set(issue Issue) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.issues[issue.Key] = issue
+}
+
+func (f *Fake) Worklogs(key string) []Worklog {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Worklog(nil), f.worklogs[key]...)
+}
+
+func (f *Fake) NormalizeKey(key string) (string, error) {
+	return strings.TrimSpace(key), nil
+}
+
+func (f *Fake) GetIssue(ctx context.Context, key string) (*Issue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	issue, ok := f.issues[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &issue, nil
+}
+
+func (f *Fake) AddWorklog(ctx context.Context, key string, worklog Worklog) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.issues[key]; !ok {
+		return "", ErrNotFound
+	}
+	f.worklogs[key] = append(f.worklogs[key], worklog)
+	f.nextID++
+	return strconv.Itoa(f.nextID), nil
+}
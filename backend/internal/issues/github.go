@@ -0,0 +1,113 @@
+package issues
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GitHubProvider reads GitHub issues through the REST API. Keys look like
+// "owner/repo#123"; issue URLs are accepted too. GitHub has no worklogs, so
+// time is posted as an issue comment.
+type GitHubProvider struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+}
+
+var (
+	githubKeyPattern = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+	githubURLPattern = regexp.MustCompile(`^https?://[^/]+/([\w.-]+)/([\w.-]+)/(?:issues|pull)/(\d+)/?$`)
+)
+
+func (g *GitHubProvider) NormalizeKey(key string) (string, error) {
+	owner, repo, number, err := g.parseKey(key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s#%d", owner, repo, number), nil
+}
+
+func (g *GitHubProvider) parseKey(key string) (owner, repo string, number int, err error) {
+	key = strings.TrimSpace(key)
+	m := githubKeyPattern.FindStringSubmatch(key)
+	if m == nil {
+		m = githubURLPattern.FindStringSubmatch(key)
+	}
+	if m == nil {
+		return "", "", 0, fmt.Errorf("GitHub issue keys look like owner/repo#123, got %q", key)
+	}
+	number, err = strconv.Atoi(m[3])
+	return m[1], m[2], number, err
+}
+
+func (g *GitHubProvider) GetIssue(ctx context.Context, key string) (*Issue, error) {
+	owner, repo, number, err := g.parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Title   string `json:"title"`
+		State   string `json:"state"`
+		HTMLURL string `json:"html_url"`
+	}
+	req, err := g.newRequest(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/issues/%d", owner, repo, number))
+	if err != nil {
+		return nil, err
+	}
+	if err := doJSON(g.Client, req, nil, &body); err != nil {
+		return nil, err
+	}
+
+	issue := &Issue{
+		Key:    fmt.Sprintf("%s/%s#%d", owner, repo, number),
+		Title:  body.Title,
+		Status: body.State,
+		State:  StateOpen,
+		URL:    body.HTMLURL,
+	}
+	if body.State == "closed" {
+		issue.State = StateDone
+	}
+	return issue, nil
+}
+
+func (g *GitHubProvider) AddWorklog(ctx context.Context, key string, worklog Worklog) (string, error) {
+	owner, repo, number, err := g.parseKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	text := fmt.Sprintf("Logged %s on %s", FormatDuration(worklog.Duration), worklog.Started.Format("2006-01-02"))
+	if worklog.Comment != "" {
+		text += ": " + worklog.Comment
+	}
+
+	var body struct {
+		ID int64 `json:"id"`
+	}
+	req, err := g.newRequest(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, number))
+	if err != nil {
+		return "", err
+	}
+	if err := doJSON(g.Client, req, map[string]string{"body": text}, &body); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(body.ID, 10), nil
+}
+
+func (g *GitHubProvider) newRequest(ctx context.Context, method, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(g.BaseURL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return req, nil
+}
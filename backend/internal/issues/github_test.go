@@ -0,0 +1,142 @@
+package issues
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitHubNormalizeKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"octo/hello-world#42", "octo/hello-world#42", false},
+		{"  octo/hello.world#007 ", "octo/hello.world#7", false},
+		{"https://github.com/octo/hello-world/issues/42", "octo/hello-world#42", false},
+		{"https://github.com/octo/hello-world/pull/42/", "octo/hello-world#42", false},
+		{"https://github.example.com/octo/repo_1/issues/3", "octo/repo_1#3", false},
+		{"octo/hello-world", "", true},
+		{"hello-world#42", "", true},
+		{"octo/hello-world#abc", "", true},
+		{"https://github.com/octo/hello-world/commits/42", "", true},
+		{"PROJ-42", "", true},
+	}
+	g := &GitHubProvider{}
+	for _, tt := range tests {
+		got, err := g.NormalizeKey(tt.key)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeKey(%q) = %q, %v; want %q, error %v", tt.key, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGitHubGetIssue(t *testing.T) {
+	tests := []struct {
+		state string
+		want  State
+	}{
+		{"open", StateOpen},
+		{"closed", StateDone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/repos/octo/hello-world/issues/42" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					t.Errorf("Authorization = %q", got)
+				}
+				json.NewEncoder(w).Encode(map[string]string{
+					"title":    "Fix the login page",
+					"state":    tt.state,
+					"html_url": "https://github.com/octo/hello-world/issues/42",
+				})
+			}))
+			defer server.Close()
+
+			g := &GitHubProvider{BaseURL: server.URL + "/", Token: "secret"}
+			issue, err := g.GetIssue(context.Background(), "https://github.com/octo/hello-world/issues/42")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Issue{
+				Key:    "octo/hello-world#42",
+				Title:  "Fix the login page",
+				Status: tt.state,
+				State:  tt.want,
+				URL:    "https://github.com/octo/hello-world/issues/42",
+			}
+			if *issue != want {
+				t.Errorf("GetIssue = %+v, want %+v", *issue, want)
+			}
+		})
+	}
+}
+
+func TestGitHubErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		g := &GitHubProvider{BaseURL: server.URL}
+		if _, err := g.GetIssue(context.Background(), "octo/hello-world#1"); !errors.Is(err, tt.want) {
+			t.Errorf("status %d: error = %v, want %v", tt.status, err, tt.want)
+		}
+		server.Close()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	g := &GitHubProvider{BaseURL: server.URL}
+	_, err := g.GetIssue(context.Background(), "octo/hello-world#1")
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("error = %v, want the response message", err)
+	}
+}
+
+func TestGitHubAddWorklog(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/octo/hello-world/issues/42/comments" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1234567890123}`))
+	}))
+	defer server.Close()
+
+	g := &GitHubProvider{BaseURL: server.URL}
+	id, err := g.AddWorklog(context.Background(), "octo/hello-world#42", Worklog{
+		Started:  time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC),
+		Duration: 90 * time.Minute,
+		Comment:  "Reproduced the bug",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "1234567890123" {
+		t.Errorf("id = %q", id)
+	}
+	if want := "Logged 1h 30m on 2024-03-05: Reproduced the bug"; got["body"] != want {
+		t.Errorf("comment = %q, want %q", got["body"], want)
+	}
+}
@@ -0,0 +1,197 @@
+// Package issues connects tasks to issues in external trackers.
+package issues
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	GitHub = "github"
+	Jira   = "jira"
+)
+
+var (
+	ErrNotFound     = errors.New("issue not found")
+	ErrUnauthorized = errors.New("issue tracker rejected the credentials")
+)
+
+// State is the coarse progress of an issue, mapped from each tracker's own
+// statuses.
+type State string
+
+const (
+	StateOpen       State = "open"
+	StateInProgress State = "in_progress"
+	StateDone       State = "done"
+)
+
+type Issue struct {
+	Key    string
+	Title  string
+	Status string // the tracker's own status name
+	State  State
+	URL    string
+}
+
+// Worklog is time logged on a task, pushed to the issue it is linked to.
+type Worklog struct {
+	Started  time.Time
+	Duration time.Duration
+	Comment  string
+}
+
+// Provider is an issue tracker.
+type Provider interface {
+	// NormalizeKey checks that key names an issue in this tracker and
+	// returns it in canonical form.
+	NormalizeKey(key string) (string, error)
+	GetIssue(ctx context.Context, key string) (*Issue, error)
+	// AddWorklog records time against the issue and returns the tracker's
+	// ID for the record.
+	AddWorklog(ctx context.Context, key string, worklog Worklog) (string, error)
+}
+
+// New returns the provider named kind. baseURL may be empty to use the
+// public GitHub API; username is the Jira account email. Trackers must be
+// reachable over https at a public address, as base URLs come from users.
+func New(kind, baseURL, username, token string) (Provider, error) {
+	switch kind {
+	case GitHub:
+		if baseURL == "" {
+			baseURL = "https://api.github.com"
+		}
+		if err := validateBaseURL(baseURL); err != nil {
+			return nil, err
+		}
+		return &GitHubProvider{BaseURL: baseURL, Token: token, Client: publicClient}, nil
+	case Jira:
+		if baseURL == "" {
+			return nil, errors.New("Jira needs a base URL")
+		}
+		if err := validateBaseURL(baseURL); err != nil {
+			return nil, err
+		}
+		return &JiraProvider{BaseURL: baseURL, Email: username, Token: token, Client: publicClient}, nil
+	default:
+		return nil, fmt.Errorf("unknown issue tracker %q", kind)
+	}
+}
+
+var errPrivateAddress = errors.New("base URL must not point to a local or private address")
+
+func validateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return errors.New("base URL is not a valid URL")
+	}
+	if u.Scheme != "https" {
+		return errors.New("base URL must use https")
+	}
+	if u.User != nil {
+		return errors.New("base URL must not contain credentials")
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// publicClient only connects to public addresses. The check runs on the
+// resolved address of every connection, redirects included, so host names
+// resolving to internal addresses are refused too.
+var publicClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return errPrivateAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP does not
+// count as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// FormatDuration renders d as "1h 30m" for worklog comments.
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	default:
+		return fmt.Sprintf("%dh %dm", h, m)
+	}
+}
+
+// doJSON sends req with an optional JSON body and decodes a JSON response
+// into out when it is not nil.
+func doJSON(client *http.Client, req *http.Request, body, out interface{}) error {
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,114 @@
+package issues
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// JiraProvider reads Jira issues through the REST API v2, authenticating with
+// an account email and API token. Keys look like "PROJ-123".
+type JiraProvider struct {
+	BaseURL string
+	Email   string
+	Token   string
+	Client  *http.Client
+}
+
+var jiraKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-\d+$`)
+
+func (j *JiraProvider) NormalizeKey(key string) (string, error) {
+	key = strings.ToUpper(strings.TrimSpace(key))
+	if !jiraKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("Jira issue keys look like PROJ-123, got %q", key)
+	}
+	return key, nil
+}
+
+func (j *JiraProvider) GetIssue(ctx context.Context, key string) (*Issue, error) {
+	key, err := j.NormalizeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	req, err := j.newRequest(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"?fields=summary,status")
+	if err != nil {
+		return nil, err
+	}
+	if err := doJSON(j.Client, req, nil, &body); err != nil {
+		return nil, err
+	}
+
+	issue := &Issue{
+		Key:    body.Key,
+		Title:  body.Fields.Summary,
+		Status: body.Fields.Status.Name,
+		URL:    strings.TrimSuffix(j.BaseURL, "/") + "/browse/" + body.Key,
+	}
+	// Jira groups statuses into the categories new, indeterminate and done
+	switch body.Fields.Status.StatusCategory.Key {
+	case "done":
+		issue.State = StateDone
+	case "indeterminate":
+		issue.State = StateInProgress
+	default:
+		issue.State = StateOpen
+	}
+	return issue, nil
+}
+
+func (j *JiraProvider) AddWorklog(ctx context.Context, key string, worklog Worklog) (string, error) {
+	key, err := j.NormalizeKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Jira rejects worklogs under a minute
+	seconds := int64(worklog.Duration.Seconds())
+	if seconds < 60 {
+		seconds = 60
+	}
+	payload := map[string]interface{}{
+		"started":          worklog.Started.Format("2006-01-02T15:04:05.000-0700"),
+		"timeSpentSeconds": seconds,
+	}
+	if worklog.Comment != "" {
+		payload["comment"] = worklog.Comment
+	}
+
+	var body struct {
+		ID string `json:"id"`
+	}
+	req, err := j.newRequest(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/worklog")
+	if err != nil {
+		return "", err
+	}
+	if err := doJSON(j.Client, req, payload, &body); err != nil {
+		return "", err
+	}
+	return body.ID, nil
+}
+
+func (j *JiraProvider) newRequest(ctx context.Context, method, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(j.BaseURL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(j.Email, j.Token)
+	return req, nil
+}
@@ -0,0 +1,120 @@
+package issues
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJiraNormalizeKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"PROJ-123", "PROJ-123", false},
+		{" proj-7 ", "PROJ-7", false},
+		{"AB_2-1", "AB_2-1", false},
+		{"P-1", "", true},
+		{"2PROJ-1", "", true},
+		{"PROJ-", "", true},
+		{"PROJ123", "", true},
+		{"PROJ-12a", "", true},
+		{"octo/hello-world#1", "", true},
+	}
+	j := &JiraProvider{}
+	for _, tt := range tests {
+		got, err := j.NormalizeKey(tt.key)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeKey(%q) = %q, %v; want %q, error %v", tt.key, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestJiraGetIssue(t *testing.T) {
+	tests := []struct {
+		status, category string
+		want             State
+	}{
+		{"To Do", "new", StateOpen},
+		{"In Review", "indeterminate", StateInProgress},
+		{"Done", "done", StateDone},
+		{"Custom", "undefined", StateOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/rest/api/2/issue/PROJ-7" || r.URL.Query().Get("fields") != "summary,status" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+				}
+				if user, pass, ok := r.BasicAuth(); !ok || user != "me@example.com" || pass != "secret" {
+					t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+				}
+				w.Write([]byte(`{"key": "PROJ-7", "fields": {"summary": "Export invoices",
+					"status": {"name": "` + tt.status + `", "statusCategory": {"key": "` + tt.category + `"}}}}`))
+			}))
+			defer server.Close()
+
+			j := &JiraProvider{BaseURL: server.URL + "/", Email: "me@example.com", Token: "secret"}
+			issue, err := j.GetIssue(context.Background(), "proj-7")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Issue{
+				Key:    "PROJ-7",
+				Title:  "Export invoices",
+				Status: tt.status,
+				State:  tt.want,
+				URL:    server.URL + "/browse/PROJ-7",
+			}
+			if *issue != want {
+				t.Errorf("GetIssue = %+v, want %+v", *issue, want)
+			}
+		})
+	}
+}
+
+func TestJiraAddWorklog(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		comment  string
+		seconds  float64
+	}{
+		{"rounded up to a minute", 20 * time.Second, "", 60},
+		{"with comment", 95 * time.Minute, "Pairing", 5700},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/rest/api/2/issue/PROJ-7/worklog" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": "10042"}`))
+			}))
+			defer server.Close()
+
+			j := &JiraProvider{BaseURL: server.URL}
+			started := time.Date(2024, 3, 5, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+			id, err := j.AddWorklog(context.Background(), "PROJ-7", Worklog{Started: started, Duration: tt.duration, Comment: tt.comment})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != "10042" {
+				t.Errorf("id = %q", id)
+			}
+			if got["started"] != "2024-03-05T09:30:00.000+0100" || got["timeSpentSeconds"] != tt.seconds {
+				t.Errorf("worklog = %v", got)
+			}
+			if comment, ok := got["comment"]; (tt.comment != "") != ok || (ok && comment != tt.comment) {
+				t.Errorf("comment = %v, want %q", comment, tt.comment)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"timetracker/internal/database"
+	"timetracker/internal/models"
+	"timetracker/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceMiddleware resolves the active workspace and checks the user
+// belongs to it. An X-Workspace-ID header wins; otherwise the workspace the
+// user last switched to is used, falling back to their personal workspace.
+func WorkspaceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.GetUserID(c)
+
+		var member models.WorkspaceMember
+		if header := c.GetHeader("X-Workspace-ID"); header != "" {
+			workspaceID, err := strconv.ParseUint(header, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace id"})
+				c.Abort()
+				return
+			}
+			if err := database.DB.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this workspace"})
+				c.Abort()
+				return
+			}
+		} else if !activeMembership(userID, &member) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "No workspace available"})
+			c.Abort()
+			return
+		}
+
+		c.Set("workspace_id", member.WorkspaceID)
+		c.Set("workspace_role", member.Role)
+		c.Next()
+	}
+}
+
+func activeMembership(userID uint, member *models.WorkspaceMember) bool {
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return false
+	}
+
+	if user.ActiveWorkspaceID != nil {
+		err := database.DB.Where("workspace_id = ? AND user_id = ?", *user.ActiveWorkspaceID, userID).First(member).Error
+		if err == nil {
+			return true
+		}
+	}
+
+	personal := database.DB.Model(&models.Workspace{}).Select("id").Where("owner_id = ? AND personal", userID)
+	return database.DB.Where("workspace_id IN (?) AND user_id = ?", personal, userID).First(member).Error == nil
+}
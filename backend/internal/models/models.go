@@ -2,6 +2,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,39 +10,468 @@ import (
 
 type User struct {
 	gorm.Model
-	Email    string    `gorm:"unique;not null" json:"email"`
-	Password string    `json:"-"`
-	Projects []Project `json:"projects"`
-	Tasks    []Task    `json:"tasks"`
+	Email             string    `gorm:"unique;not null" json:"email"`
+	Password          string    `json:"-"`
+	BaseCurrency      string    `gorm:"size:3;default:USD" json:"base_currency"`
+	ActiveWorkspaceID *uint     `json:"active_workspace_id"`
+	CostRate          float64   `json:"-"` // internal cost per hour in BaseCurrency
+	Projects          []Project `json:"projects"`
+	Tasks             []Task    `json:"tasks"`
+}
+
+// AfterCreate gives every new user a personal workspace.
+func (u *User) AfterCreate(tx *gorm.DB) error {
+	workspace := Workspace{
+		Name:     "Personal",
+		Personal: true,
+		OwnerID:  u.ID,
+	}
+	if err := tx.Create(&workspace).Error; err != nil {
+		return err
+	}
+	u.ActiveWorkspaceID = &workspace.ID
+	return tx.Model(u).Update("active_workspace_id", workspace.ID).Error
+}
+
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
+// Workspace owns clients, projects, tasks and time entries. Each user has a
+// personal workspace and may belong to shared ones.
+type Workspace struct {
+	gorm.Model
+	Name     string `json:"name"`
+	Personal bool   `json:"personal"`
+	OwnerID  uint   `json:"owner_id"`
+}
+
+// AfterCreate makes the creator the owner of a new workspace.
+func (w *Workspace) AfterCreate(tx *gorm.DB) error {
+	return tx.Create(&WorkspaceMember{
+		WorkspaceID: w.ID,
+		UserID:      w.OwnerID,
+		Role:        WorkspaceRoleOwner,
+	}).Error
+}
+
+type WorkspaceMember struct {
+	gorm.Model
+	WorkspaceID uint      `gorm:"uniqueIndex:idx_workspace_member" json:"workspace_id"`
+	UserID      uint      `gorm:"uniqueIndex:idx_workspace_member" json:"user_id"`
+	Role        string    `json:"role"`
+	User        User      `json:"user"`
+	Workspace   Workspace `json:"workspace"`
+}
+
+type Client struct {
+	gorm.Model
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Notes       string    `json:"notes"`
+	WorkspaceID uint      `gorm:"index" json:"workspace_id"`
+	UserID      uint      `json:"user_id"`
+	Projects    []Project `json:"projects"`
 }
 
 type Project struct {
 	gorm.Model
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	HourlyRate  float64     `json:"hourly_rate"`
-	UserID      uint        `json:"user_id"`
-	TimeEntries []TimeEntry `json:"time_entries"`
-	Tasks       []Task      `json:"tasks"`
+	Name         string      `json:"name"`
+	Description  string      `json:"description"`
+	HourlyRate   float64     `json:"hourly_rate"`
+	Currency     string      `gorm:"size:3;default:USD" json:"currency"`
+	BudgetHours  float64     `json:"budget_hours"`
+	BudgetAmount float64     `json:"budget_amount"`
+	ClientID     *uint       `json:"client_id"`
+	ParentID     *uint       `gorm:"index" json:"parent_id"`
+	CustomFields JSONMap     `gorm:"type:jsonb" json:"custom_fields"`
+	WorkspaceID  uint        `gorm:"index" json:"workspace_id"`
+	UserID       uint        `json:"user_id"`
+	TimeEntries  []TimeEntry `json:"time_entries"`
+	Tasks        []Task      `json:"tasks"`
+	Milestones   []Milestone `json:"milestones"`
+}
+
+// AfterCreate makes the creator the owner of a new project.
+func (p *Project) AfterCreate(tx *gorm.DB) error {
+	return tx.Create(&ProjectMember{
+		ProjectID: p.ID,
+		UserID:    p.UserID,
+		Role:      ProjectRoleOwner,
+	}).Error
+}
+
+const (
+	ProjectRoleOwner   = "owner"
+	ProjectRoleManager = "manager"
+	ProjectRoleMember  = "member"
+	ProjectRoleViewer  = "viewer"
+)
+
+type ProjectMember struct {
+	gorm.Model
+	ProjectID uint     `gorm:"uniqueIndex:idx_project_member" json:"project_id"`
+	UserID    uint     `gorm:"uniqueIndex:idx_project_member" json:"user_id"`
+	Role      string   `json:"role"`
+	CostRate  *float64 `json:"-"` // overrides the user's cost rate, in the project currency
+	User      User     `json:"user"`
+}
+
+type ProjectInvitation struct {
+	gorm.Model
+	ProjectID   uint       `json:"project_id"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	Token       string     `gorm:"uniqueIndex" json:"token"`
+	InvitedByID uint       `json:"invited_by_id"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+	Project     Project    `json:"project"`
 }
 
 type TimeEntry struct {
 	gorm.Model
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time"`
-	Duration  int64     `json:"duration"` // in seconds
-	ProjectID uint      `json:"project_id"`
-	TaskID    uint      `json:"task_id"`
-	UserID    uint      `json:"user_id"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	Duration     int64     `json:"duration"` // in seconds
+	Running      bool      `json:"running"`
+	Notes        string    `json:"notes"`
+	Tags         []Tag     `gorm:"many2many:time_entry_tags" json:"tags"`
+	ProjectID    uint      `json:"project_id"`
+	TaskID       uint      `json:"task_id"`
+	CustomFields JSONMap   `gorm:"type:jsonb" json:"custom_fields"`
+	WorkspaceID  uint      `gorm:"index" json:"workspace_id"`
+	UserID       uint      `json:"user_id"`
+
+	// WorklogID is the issue tracker's record of the entry once pushed
+	WorklogID string     `json:"worklog_id"`
+	WorklogAt *time.Time `json:"worklog_at"`
 }
 
+const (
+	TaskStatusTodo       = "TODO"
+	TaskStatusInProgress = "IN_PROGRESS"
+	TaskStatusOnHold     = "ON_HOLD"
+	TaskStatusCompleted  = "COMPLETED"
+)
+
+const (
+	TaskPriorityLow    = "LOW"
+	TaskPriorityMedium = "MEDIUM"
+	TaskPriorityHigh   = "HIGH"
+	TaskPriorityUrgent = "URGENT"
+)
+
 type Task struct {
 	gorm.Model
-	Title       string      `json:"title"`
-	Description string      `json:"description"`
-	Status      string      `json:"status"`
-	Tags        []string    `gorm:"type:text[]" json:"tags"`
-	ProjectID   uint        `json:"project_id"`
-	UserID      uint        `json:"user_id"`
-	TimeEntries []TimeEntry `json:"time_entries"`
+	Title          string      `json:"title"`
+	Description    string      `json:"description"`
+	Status         string      `gorm:"default:TODO" json:"status"`
+	StartedAt      *time.Time  `json:"started_at"`
+	CompletedAt    *time.Time  `json:"completed_at"`
+	Tags           []Tag       `gorm:"many2many:task_tags" json:"tags"`
+	EstimatedHours float64     `json:"estimated_hours"`
+	DueDate        *time.Time  `gorm:"index" json:"due_date"`
+	Priority       string      `gorm:"default:MEDIUM" json:"priority"`
+	Position       int         `json:"position"` // order within the status column of the project board
+	ProjectID      uint        `json:"project_id"`
+	ParentID       *uint       `gorm:"index" json:"parent_id"`
+	MilestoneID    *uint       `json:"milestone_id"`
+	CustomFields   JSONMap     `gorm:"type:jsonb" json:"custom_fields"`
+	WorkspaceID    uint        `gorm:"index" json:"workspace_id"`
+	UserID         uint        `json:"user_id"`
+	TimeEntries    []TimeEntry `json:"time_entries"`
+
+	// ExternalKey links the task to an issue in the workspace's issue tracker
+	// named by ExternalProvider, such as "owner/repo#12" or "PROJ-12"
+	ExternalProvider string     `json:"external_provider"`
+	ExternalKey      string     `gorm:"index" json:"external_key"`
+	ExternalURL      string     `json:"external_url"`
+	ExternalStatus   string     `json:"external_status"`
+	ExternalSyncedAt *time.Time `json:"external_synced_at"`
+
+	// Recurrence is an iCalendar RRULE expanded from RecurrenceStart. Each
+	// instance of a series is due on one occurrence; the next instance is
+	// created when it is completed or falls due.
+	Recurrence      string     `json:"recurrence"`
+	RecurrenceStart *time.Time `json:"recurrence_start"`
+	SeriesID        *uint      `gorm:"index" json:"series_id"` // first task of the series
+	NextInstanceID  *uint      `json:"next_instance_id"`
+
+	Checklist []TaskChecklistItem `json:"checklist,omitempty"`
+
+	// TrackedHours includes the time tracked on subtasks
+	TrackedHours   float64 `gorm:"-" json:"tracked_hours"`
+	OverEstimate   bool    `gorm:"-" json:"over_estimate"`
+	SubtaskCount   int     `gorm:"-" json:"subtask_count"`
+	SubtasksDone   int     `gorm:"-" json:"subtasks_done"`
+	ChecklistCount int     `gorm:"-" json:"checklist_count"`
+	ChecklistDone  int     `gorm:"-" json:"checklist_done"`
+	Blocked        bool    `gorm:"-" json:"blocked"`
+
+	// UnblockedTasks lists the tasks that completing this task unblocked
+	UnblockedTasks []Task `gorm:"-" json:"unblocked_tasks,omitempty"`
+}
+
+// TaskDependency records that TaskID cannot start before BlockedByID is
+// completed.
+type TaskDependency struct {
+	gorm.Model
+	TaskID      uint `gorm:"uniqueIndex:idx_task_dependency" json:"task_id"`
+	BlockedByID uint `gorm:"uniqueIndex:idx_task_dependency;index" json:"blocked_by_id"`
+}
+
+type TaskChecklistItem struct {
+	gorm.Model
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"`
+	TaskID   uint   `gorm:"index" json:"task_id"`
+}
+
+type TaskComment struct {
+	gorm.Model
+	Body   string `json:"body"`
+	TaskID uint   `gorm:"index" json:"task_id"`
+	UserID uint   `json:"user_id"`
+	User   User   `json:"user"`
+}
+
+// TaskStatusChange records a task moving between statuses. UserID is nil
+// when the change followed from the task's subtasks or checklist.
+type TaskStatusChange struct {
+	gorm.Model
+	TaskID     uint   `gorm:"index" json:"task_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	UserID     *uint  `json:"user_id"`
+}
+
+// IssueTracker holds a workspace's connection to an external issue tracker,
+// one per provider.
+type IssueTracker struct {
+	gorm.Model
+	Provider    string `gorm:"uniqueIndex:idx_issue_tracker" json:"provider"`
+	BaseURL     string `json:"base_url"`
+	Username    string `json:"username"` // account email for Jira
+	Token       string `json:"-"`
+	WorkspaceID uint   `gorm:"uniqueIndex:idx_issue_tracker" json:"workspace_id"`
+}
+
+// Attachment is a file uploaded to a project, or to one of its tasks when
+// TaskID is set. StorageKey locates the content in the storage backend.
+type Attachment struct {
+	gorm.Model
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"` // in bytes
+	StorageKey  string `gorm:"uniqueIndex" json:"-"`
+	ProjectID   uint   `gorm:"index" json:"project_id"`
+	TaskID      *uint  `gorm:"index" json:"task_id"`
+	WorkspaceID uint   `gorm:"index" json:"workspace_id"`
+	UserID      uint   `json:"user_id"`
+}
+
+const (
+	MilestoneStatusPending    = "PENDING"
+	MilestoneStatusInProgress = "IN_PROGRESS"
+	MilestoneStatusCompleted  = "COMPLETED"
+)
+
+type Milestone struct {
+	gorm.Model
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	DueDate      *time.Time `json:"due_date"`
+	Amount       float64    `json:"amount"`
+	Status       string     `gorm:"default:PENDING" json:"status"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	ProjectID    uint       `json:"project_id"`
+	UserID       uint       `json:"user_id"`
+	Tasks        []Task     `json:"tasks"`
+	TrackedHours float64    `gorm:"-" json:"tracked_hours"`
+}
+
+const (
+	InvoiceStatusDraft         = "draft"
+	InvoiceStatusIssued        = "issued"
+	InvoiceStatusSent          = "sent"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusVoid          = "void"
+)
+
+const DefaultPaymentTermsDays = 30
+
+// Invoice bills a project's work over a period, or a completed milestone.
+// The line items of a draft are recomputed from the time entries whenever it
+// is updated; issuing an invoice freezes them. Issued invoices move on to
+// sent, partially_paid and paid, or are voided.
+type Invoice struct {
+	gorm.Model
+	Number             string            `gorm:"index" json:"number"` // given when issued, unique in the workspace
+	SequenceID         *uint             `json:"sequence_id"`
+	Status             string            `gorm:"default:draft;index" json:"status"`
+	ProjectID          uint              `gorm:"index" json:"project_id"`
+	ProjectName        string            `json:"project_name"`
+	MilestoneID        *uint             `json:"milestone_id"`
+	StartDate          time.Time         `gorm:"type:date" json:"start_date"`
+	EndDate            time.Time         `gorm:"type:date" json:"end_date"`
+	IncludeSubprojects bool              `json:"include_subprojects"`
+	Currency           string            `gorm:"size:3" json:"currency"`
+	TotalHours         float64           `json:"total_hours"`
+	TotalAmount        float64           `json:"total_amount"`
+	Notes              string            `json:"notes"`
+	CustomFields       JSONMap           `gorm:"type:jsonb" json:"custom_fields"` // project fields as billed
+	PaymentTermsDays   int               `json:"payment_terms_days"`              // net days, 0 for due on receipt
+	DueDate            *time.Time        `gorm:"type:date" json:"due_date"`       // set when issued
+	AmountPaid         float64           `json:"amount_paid"`
+	Overdue            bool              `gorm:"index" json:"overdue"` // flagged by the scheduler
+	IssuedAt           *time.Time        `json:"issued_at"`
+	SentAt             *time.Time        `json:"sent_at"`
+	PaidAt             *time.Time        `json:"paid_at"`
+	VoidedAt           *time.Time        `json:"voided_at"`
+	WorkspaceID        uint              `gorm:"index" json:"workspace_id"`
+	UserID             uint              `json:"user_id"`
+	LineItems          []InvoiceLineItem `json:"line_items,omitempty"`
+	Payments           []InvoicePayment  `json:"payments,omitempty"`
+}
+
+// InvoiceLineItem is one billed line: a day of work, the work on one
+// project of an invoice including sub-projects, or a fixed milestone amount.
+// HourlyRate is in Currency; Amount is in the invoice currency.
+type InvoiceLineItem struct {
+	gorm.Model
+	InvoiceID   uint       `gorm:"index" json:"invoice_id"`
+	Position    int        `json:"position"`
+	Description string     `json:"description"`
+	Date        *time.Time `gorm:"type:date" json:"date"`
+	ProjectID   *uint      `json:"project_id"`
+	Hours       float64    `json:"hours"`
+	HourlyRate  float64    `json:"hourly_rate"`
+	Currency    string     `gorm:"size:3" json:"currency"`
+	Amount      float64    `json:"amount"`
+	Fields      JSONMap    `gorm:"type:jsonb" json:"fields"` // time entry custom fields of the day
+}
+
+// InvoicePayment records money received against an invoice, in the invoice
+// currency.
+type InvoicePayment struct {
+	gorm.Model
+	InvoiceID uint      `gorm:"index" json:"invoice_id"`
+	Amount    float64   `json:"amount"`
+	Date      time.Time `gorm:"type:date" json:"date"`
+	Note      string    `json:"note"`
+	UserID    uint      `json:"user_id"`
+}
+
+const DefaultInvoiceNumberFormat = "INV-{YYYY}-{0000}"
+
+// InvoiceSequence hands out gapless invoice numbers in a format such as
+// INV-{YYYY}-{0000}. A workspace has one shared sequence; a member may keep a
+// personal one (UserID set) for the invoices they issue. LastNumber is the
+// counter of Year, restarting each year when ResetYearly is set.
+type InvoiceSequence struct {
+	gorm.Model
+	Format      string `json:"format"`
+	ResetYearly bool   `json:"reset_yearly"`
+	Year        int    `json:"year"`
+	LastNumber  int    `json:"last_number"`
+	WorkspaceID uint   `gorm:"uniqueIndex:idx_invoice_sequence" json:"workspace_id"`
+	UserID      uint   `gorm:"uniqueIndex:idx_invoice_sequence" json:"user_id"` // 0 for the workspace sequence
+
+	NextNumber string `gorm:"-" json:"next_number"`
+}
+
+// ProjectTemplate captures the reusable setup of a project so new client
+// engagements can start from it.
+type ProjectTemplate struct {
+	gorm.Model
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	HourlyRate   float64               `json:"hourly_rate"`
+	Currency     string                `gorm:"size:3;default:USD" json:"currency"`
+	BudgetHours  float64               `json:"budget_hours"`
+	BudgetAmount float64               `json:"budget_amount"`
+	WorkspaceID  uint                  `gorm:"index" json:"workspace_id"`
+	UserID       uint                  `json:"user_id"`
+	Tasks        []ProjectTemplateTask `gorm:"foreignKey:TemplateID" json:"tasks"`
+}
+
+// ProjectTemplateTask is a task of a template. Tasks keep the order they
+// were saved in, by id.
+type ProjectTemplateTask struct {
+	gorm.Model
+	Title          string  `json:"title"`
+	Description    string  `json:"description"`
+	Tags           []Tag   `gorm:"many2many:project_template_task_tags" json:"tags"`
+	EstimatedHours float64 `json:"estimated_hours"`
+	TemplateID     uint    `json:"template_id"`
+}
+
+// ExchangeRate is the number of QuoteCurrency units one BaseCurrency unit buys
+// on Date. Rates are shared by a workspace, one per day and currency pair; the
+// unique index is created by the database backfill.
+type ExchangeRate struct {
+	gorm.Model
+	Date          time.Time `gorm:"type:date" json:"date"`
+	BaseCurrency  string    `gorm:"size:3" json:"base_currency"`
+	QuoteCurrency string    `gorm:"size:3" json:"quote_currency"`
+	Rate          float64   `json:"rate"`
+	WorkspaceID   uint      `gorm:"index" json:"workspace_id"`
+	UserID        uint      `json:"user_id"`
+}
+
+const (
+	CustomFieldEntityProject   = "project"
+	CustomFieldEntityTask      = "task"
+	CustomFieldEntityTimeEntry = "time_entry"
+)
+
+const (
+	CustomFieldTypeText   = "text"
+	CustomFieldTypeNumber = "number"
+	CustomFieldTypeDate   = "date"
+	CustomFieldTypeSelect = "select"
+)
+
+// CustomField defines a user-defined field that projects, tasks or time
+// entries of a workspace can carry in their CustomFields.
+type CustomField struct {
+	gorm.Model
+	EntityType  string   `gorm:"uniqueIndex:idx_custom_field_key" json:"entity_type"`
+	Key         string   `gorm:"uniqueIndex:idx_custom_field_key" json:"key"`
+	Label       string   `json:"label"`
+	Type        string   `json:"type"`
+	Options     []string `gorm:"type:text[]" json:"options"`
+	Required    bool     `json:"required"`
+	WorkspaceID uint     `gorm:"uniqueIndex:idx_custom_field_key" json:"workspace_id"`
+	UserID      uint     `json:"user_id"`
+}
+
+const DefaultTagColor = "#6B7280"
+
+// Tag labels tasks and time entries within a workspace. Names are unique per
+// workspace regardless of case; that index is created by the database
+// backfill.
+type Tag struct {
+	gorm.Model
+	Name        string `gorm:"uniqueIndex:idx_tag_name" json:"name"`
+	Color       string `gorm:"size:7" json:"color"`
+	WorkspaceID uint   `gorm:"uniqueIndex:idx_tag_name" json:"workspace_id"`
+	UserID      uint   `json:"user_id"`
+}
+
+// UnmarshalJSON accepts a plain tag name as well as a tag object, so requests
+// may send tags as ["bug", "frontend"].
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = Tag{Name: name}
+		return nil
+	}
+
+	type tag Tag
+	return json.Unmarshal(data, (*tag)(t))
 }
@@ -0,0 +1,161 @@
+// Package numbering formats sequential document numbers such as
+// INV-2024-0007 from a pattern.
+//
+// Patterns are literal text with placeholders in braces: {YYYY} and {YY} for
+// the year, {MM} for the month and a run of zeros, such as {0000}, for the
+// counter padded to that many digits.
+package numbering
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Validate checks that pattern has exactly one counter and known
+// placeholders. A counter reset every year needs the year in the pattern, or
+// numbers would repeat.
+func Validate(pattern string, resetYearly bool) error {
+	if strings.TrimSpace(pattern) == "" {
+		return errors.New("format is required")
+	}
+	if len(pattern) > 64 {
+		return errors.New("format is too long")
+	}
+
+	counters, hasYear := 0, false
+	for _, m := range placeholderPattern.FindAllStringSubmatch(pattern, -1) {
+		switch name := m[1]; {
+		case name == "YYYY" || name == "YY":
+			hasYear = true
+		case name == "MM":
+		case name != "" && strings.Trim(name, "0") == "":
+			counters++
+		default:
+			return fmt.Errorf("unknown placeholder {%s}", name)
+		}
+	}
+	if strings.ContainsAny(placeholderPattern.ReplaceAllString(pattern, ""), "{}") {
+		return errors.New("format has unbalanced braces")
+	}
+	if counters != 1 {
+		return errors.New("format needs exactly one counter such as {0000}")
+	}
+	if resetYearly && !hasYear {
+		return errors.New("format needs {YYYY} or {YY} when the counter resets yearly")
+	}
+	return nil
+}
+
+// Format renders number n of pattern for a document dated date.
+func Format(pattern string, date time.Time, n int) string {
+	return placeholderPattern.ReplaceAllStringFunc(pattern, func(m string) string {
+		switch name := m[1 : len(m)-1]; name {
+		case "YYYY":
+			return fmt.Sprintf("%04d", date.Year())
+		case "YY":
+			return fmt.Sprintf("%02d", date.Year()%100)
+		case "MM":
+			return fmt.Sprintf("%02d", int(date.Month()))
+		default:
+			return fmt.Sprintf("%0*d", len(name), n)
+		}
+	})
+}
+
+// Overlaps reports whether patterns a and b can render the same number,
+// whatever the dates and counters. Years and months are taken as any digits
+// and counters as their padding followed by any further digits, so the
+// answer errs on the side of an overlap.
+func Overlaps(a, b string) bool {
+	ta, tb := tokenize(a), tokenize(b)
+	seen := make(map[[2]int]bool)
+
+	// walk reports whether the rest of a from i and of b from j can render
+	// the same text
+	var walk func(i, j int) bool
+	walk = func(i, j int) bool {
+		if seen[[2]int{i, j}] {
+			return false
+		}
+		seen[[2]int{i, j}] = true
+
+		if i == len(ta) && j == len(tb) {
+			return true
+		}
+		if i < len(ta) && ta[i].repeat && walk(i+1, j) {
+			return true
+		}
+		if j < len(tb) && tb[j].repeat && walk(i, j+1) {
+			return true
+		}
+		if i < len(ta) && j < len(tb) && ta[i].matches(tb[j]) {
+			ni, nj := i+1, j+1
+			if ta[i].repeat {
+				ni = i
+			}
+			if tb[j].repeat {
+				nj = j
+			}
+			return walk(ni, nj)
+		}
+		return false
+	}
+	return walk(0, 0)
+}
+
+// token is one character of rendered output: a literal byte or a digit,
+// which repeat allows any number of times.
+type token struct {
+	literal byte
+	digit   bool
+	repeat  bool
+}
+
+func (t token) matches(o token) bool {
+	switch {
+	case t.digit && o.digit:
+		return true
+	case t.digit:
+		return o.literal >= '0' && o.literal <= '9'
+	case o.digit:
+		return t.literal >= '0' && t.literal <= '9'
+	default:
+		return t.literal == o.literal
+	}
+}
+
+func tokenize(pattern string) []token {
+	var tokens []token
+	digits := func(n int) {
+		for i := 0; i < n; i++ {
+			tokens = append(tokens, token{digit: true})
+		}
+	}
+
+	last := 0
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(pattern, -1) {
+		for i := last; i < m[0]; i++ {
+			tokens = append(tokens, token{literal: pattern[i]})
+		}
+		last = m[1]
+
+		switch name := pattern[m[2]:m[3]]; name {
+		case "YYYY":
+			digits(4)
+		case "YY", "MM":
+			digits(2)
+		default:
+			digits(len(name))
+			tokens = append(tokens, token{digit: true, repeat: true})
+		}
+	}
+	for i := last; i < len(pattern); i++ {
+		tokens = append(tokens, token{literal: pattern[i]})
+	}
+	return tokens
+}
@@ -0,0 +1,95 @@
+package numbering
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		resetYearly bool
+		err         string
+	}{
+		{"INV-{YYYY}-{0000}", true, ""},
+		{"{YY}{MM}/{000}", true, ""},
+		{"INV-{0000}", false, ""},
+		{"{YYYY}{0}", true, ""},
+		{"", true, "format is required"},
+		{"   ", false, "format is required"},
+		{"INV-{YYYY}-{0000}-" + string(make([]byte, 50)), true, "format is too long"},
+		{"INV-{0000}", true, "format needs {YYYY} or {YY} when the counter resets yearly"},
+		{"INV-{YYYY}", true, "format needs exactly one counter such as {0000}"},
+		{"{YYYY}-{000}-{000}", true, "format needs exactly one counter such as {0000}"},
+		{"{YYYY}-{DD}-{000}", true, "unknown placeholder {DD}"},
+		{"{YYYY}-{}-{000}", true, "unknown placeholder {}"},
+		{"{YYYY}-{0001}", true, "unknown placeholder {0001}"},
+		{"INV-{YYYY-{0000}", true, "format has unbalanced braces"},
+		{"INV-{YYYY}}-{0000}", true, "format has unbalanced braces"},
+		{"INV-{YYYY}-{0000", true, "format has unbalanced braces"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.pattern, tt.resetYearly)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.err {
+			t.Errorf("Validate(%q, %v) = %q, want %q", tt.pattern, tt.resetYearly, got, tt.err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		pattern string
+		n       int
+		want    string
+	}{
+		{"INV-{YYYY}-{0000}", 7, "INV-2026-0007"},
+		{"INV-{YYYY}-{0000}", 12345, "INV-2026-12345"},
+		{"{YY}{MM}/{000}", 42, "2603/042"},
+		{"{0}", 9, "9"},
+		{"N{MM}.{YY}-{00}", 100, "N03.26-100"},
+		{"A{YY}", 1, "A26"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.pattern, date, tt.n); got != tt.want {
+			t.Errorf("Format(%q, %d) = %q, want %q", tt.pattern, tt.n, got, tt.want)
+		}
+	}
+
+	if got := Format("{YY}", time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), 1); got != "05" {
+		t.Errorf("Format({YY}) in 2005 = %q, want 05", got)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"INV-{YYYY}-{0000}", "INV-{YYYY}-{0000}", true},
+		{"INV-{YYYY}-{0000}", "INV-{YYYY}-{000}", true}, // INV-2026-1000
+		{"INV-{YYYY}-{0000}", "INV-{YY}{MM}-{0000}", true},
+		{"INV-{YYYY}-{0000}", "INV-2026-{0000}", true},
+		{"INV-{YYYY}-{0000}", "ANN-{YYYY}-{0000}", false},
+		{"INV-{YYYY}-{0000}", "INV-{YYYY}/{0000}", false},
+		{"INV-{YYYY}-{0000}", "INV-{YYYY}-A{000}", false},
+		{"INV-{YYYY}-{0000}", "INV-{YY}-{0000}", false}, // the year part has 4 digits or 2
+		{"{0000}", "{00}", true},
+		{"{0000}", "X{0000}", false},
+		{"{YYYY}{0000}", "{YY}{000}", true}, // eight digits or more each way
+		{"{YYYY}-{0}", "{YYYY}-{0}A", false},
+	}
+	for _, tt := range tests {
+		if got := Overlaps(tt.a, tt.b); got != tt.want {
+			t.Errorf("Overlaps(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := Overlaps(tt.b, tt.a); got != tt.want {
+			t.Errorf("Overlaps(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,466 @@
+// Package rrule parses and expands the subset of iCalendar (RFC 5545)
+// recurrence rules used for recurring tasks: FREQ, INTERVAL, COUNT, UNTIL,
+// BYDAY, BYMONTHDAY, BYMONTH and WKST.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds the expansion of rules that can never match, such as
+// FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30.
+const maxPeriods = 50000
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// WeekdayNum is a BYDAY entry: a weekday, optionally the Nth (or, when
+// negative, the Nth last) of the month or year.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// Parse reads a rule such as "FREQ=MONTHLY;BYDAY=-1FR". A leading "RRULE:"
+// is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	if s == "" {
+		return nil, errors.New("empty rule")
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s given more than once", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch Frequency(value) {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = Frequency(value)
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err != nil || r.Interval < 1 {
+				return nil, errors.New("INTERVAL must be a positive number")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err != nil || r.Count < 1 {
+				return nil, errors.New("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, err := parseWeekdayNum(v)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %s", v)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %s", v)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			day, ok := weekdayCodes[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %s", value)
+			}
+			r.WeekStart = day
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL cannot both be set")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return nil, errors.New("numbered BYDAY is only allowed with MONTHLY or YEARLY")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return nil, errors.New("BYMONTHDAY is not allowed with WEEKLY")
+	}
+
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date-only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %s", value)
+}
+
+func parseWeekdayNum(v string) (WeekdayNum, error) {
+	if len(v) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %s", v)
+	}
+	day, ok := weekdayCodes[v[len(v)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %s", v)
+	}
+
+	n := 0
+	if prefix := v[:len(v)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %s", v)
+		}
+	}
+	return WeekdayNum{Weekday: day, N: n}, nil
+}
+
+// String returns the rule in canonical form, without the RRULE: prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = weekdayCode(day.Weekday)
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, month := range r.ByMonth {
+			months[i] = strconv.Itoa(int(month))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayCode(r.WeekStart))
+	}
+	return strings.Join(parts, ";")
+}
+
+func weekdayCode(day time.Weekday) string {
+	for code, d := range weekdayCodes {
+		if d == day {
+			return code
+		}
+	}
+	return ""
+}
+
+// After returns the first occurrence of the series starting at dtstart that
+// is strictly after t.
+func (r *Rule) After(dtstart, t time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.each(dtstart, func(occurrence time.Time) bool {
+		if occurrence.After(t) {
+			next, found = occurrence, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// Between returns up to limit occurrences after from and not after to.
+func (r *Rule) Between(dtstart, from, to time.Time, limit int) []time.Time {
+	var occurrences []time.Time
+	r.each(dtstart, func(occurrence time.Time) bool {
+		if occurrence.After(to) || len(occurrences) >= limit {
+			return false
+		}
+		if occurrence.After(from) {
+			occurrences = append(occurrences, occurrence)
+		}
+		return true
+	})
+	return occurrences
+}
+
+// each calls fn with every occurrence in order until fn returns false or the
+// series ends. Occurrences keep dtstart's time of day and location; dtstart
+// itself only counts when it matches the rule.
+func (r *Rule) each(dtstart time.Time, fn func(time.Time) bool) {
+	count := 0
+	for k := 0; k < maxPeriods; k++ {
+		for _, occurrence := range r.period(dtstart, k) {
+			if occurrence.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && occurrence.After(r.Until) {
+				return
+			}
+			count++
+			if !fn(occurrence) {
+				return
+			}
+			if r.Count > 0 && count >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+// period returns the sorted candidate occurrences of the kth period after
+// dtstart's.
+func (r *Rule) period(dtstart time.Time, k int) []time.Time {
+	year, month, day := dtstart.Date()
+	step := k * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		days = []time.Time{date(year, month, day+step, dtstart)}
+	case Weekly:
+		offset := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+		start := date(year, month, day-offset+7*step, dtstart)
+		for i := 0; i < 7; i++ {
+			d := start.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && d.Weekday() == dtstart.Weekday() || r.matchesWeekday(d) {
+				days = append(days, d)
+			}
+		}
+	case Monthly:
+		first := date(year, month+time.Month(step), 1, dtstart)
+		days = r.monthDays(first.Year(), first.Month(), dtstart)
+	case Yearly:
+		y := year + step
+		switch {
+		case len(r.ByMonth) > 0:
+			for _, m := range r.ByMonth {
+				days = append(days, r.monthDays(y, m, dtstart)...)
+			}
+		case len(r.ByMonthDay) > 0:
+			for m := time.January; m <= time.December; m++ {
+				days = append(days, r.monthDays(y, m, dtstart)...)
+			}
+		case len(r.ByDay) > 0:
+			days = r.selectWeekdays(scopeDays(date(y, time.January, 1, dtstart), date(y+1, time.January, 1, dtstart)))
+		default:
+			if d := date(y, month, day, dtstart); d.Day() == day {
+				days = []time.Time{d}
+			}
+		}
+	}
+
+	var result []time.Time
+	for _, d := range days {
+		if r.matchesMonth(d) && (r.Freq != Daily || r.matchesMonthDay(d) && (len(r.ByDay) == 0 || r.matchesWeekday(d))) {
+			result = append(result, d)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return dedupe(result)
+}
+
+// monthDays expands BYMONTHDAY and BYDAY within one month, falling back to
+// dtstart's day of the month.
+func (r *Rule) monthDays(year int, month time.Month, dtstart time.Time) []time.Time {
+	first := date(year, month, 1, dtstart)
+	all := scopeDays(first, first.AddDate(0, 1, 0))
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if dtstart.Day() <= len(all) {
+			return []time.Time{all[dtstart.Day()-1]}
+		}
+		return nil
+	}
+
+	days := all
+	if len(r.ByMonthDay) > 0 {
+		days = nil
+		for _, d := range all {
+			if r.matchesMonthDay(d) {
+				days = append(days, d)
+			}
+		}
+	}
+	if len(r.ByDay) > 0 {
+		selected := make(map[int]bool)
+		for _, d := range r.selectWeekdays(all) {
+			selected[d.Day()] = true
+		}
+		var filtered []time.Time
+		for _, d := range days {
+			if selected[d.Day()] {
+				filtered = append(filtered, d)
+			}
+		}
+		days = filtered
+	}
+	return days
+}
+
+// selectWeekdays picks the BYDAY days out of a month or year, honouring
+// ordinals relative to that scope.
+func (r *Rule) selectWeekdays(scope []time.Time) []time.Time {
+	byWeekday := make(map[time.Weekday][]time.Time)
+	for _, d := range scope {
+		byWeekday[d.Weekday()] = append(byWeekday[d.Weekday()], d)
+	}
+
+	var days []time.Time
+	for _, wd := range r.ByDay {
+		matches := byWeekday[wd.Weekday]
+		switch {
+		case wd.N == 0:
+			days = append(days, matches...)
+		case wd.N > 0 && wd.N <= len(matches):
+			days = append(days, matches[wd.N-1])
+		case wd.N < 0 && -wd.N <= len(matches):
+			days = append(days, matches[len(matches)+wd.N])
+		}
+	}
+	return days
+}
+
+func (r *Rule) matchesWeekday(d time.Time) bool {
+	for _, wd := range r.ByDay {
+		if wd.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := date(d.Year(), d.Month()+1, 0, d).Day()
+	for _, n := range r.ByMonthDay {
+		if n == d.Day() || n < 0 && last+n+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonth(d time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == d.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+// date builds a day with the clock time and location of ref. Out of range
+// days roll over as with time.Date.
+func date(year int, month time.Month, day int, ref time.Time) time.Time {
+	return time.Date(year, month, day, ref.Hour(), ref.Minute(), ref.Second(), 0, ref.Location())
+}
+
+func scopeDays(from, to time.Time) []time.Time {
+	var days []time.Time
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days
+}
+
+func dedupe(days []time.Time) []time.Time {
+	var result []time.Time
+	for i, d := range days {
+		if i == 0 || !d.Equal(days[i-1]) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
@@ -0,0 +1,216 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// expand lists the first n occurrences, or fewer when the series ends.
+func expand(t *testing.T, rule string, dtstart time.Time, n int) []string {
+	t.Helper()
+	r, err := Parse(rule)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", rule, err)
+	}
+	var days []string
+	for _, d := range r.Between(dtstart, dtstart.Add(-time.Second), dtstart.AddDate(200, 0, 0), n) {
+		if d.Hour() != dtstart.Hour() || d.Minute() != dtstart.Minute() {
+			t.Errorf("%s: occurrence %v lost the time of day", rule, d)
+		}
+		days = append(days, d.Format("2006-01-02"))
+	}
+	return days
+}
+
+func at(date string) time.Time {
+	d, err := time.Parse("2006-01-02 15:04", date+" 09:00")
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Dates in 1997 to 2000 come from the examples of RFC 5545 section 3.8.5.3,
+// less the ones relying on parts this package does not support.
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		n       int
+		want    string // space separated, "" when there is none
+	}{
+		{"daily for 10 occurrences", "FREQ=DAILY;COUNT=10", "1997-09-02", 20,
+			"1997-09-02 1997-09-03 1997-09-04 1997-09-05 1997-09-06 1997-09-07 1997-09-08 1997-09-09 1997-09-10 1997-09-11"},
+		{"every 10 days, 5 occurrences", "FREQ=DAILY;INTERVAL=10;COUNT=5", "1997-09-02", 20,
+			"1997-09-02 1997-09-12 1997-09-22 1997-10-02 1997-10-12"},
+		{"every other day", "FREQ=DAILY;INTERVAL=2", "1997-09-02", 4,
+			"1997-09-02 1997-09-04 1997-09-06 1997-09-08"},
+		{"date-only UNTIL includes the day", "FREQ=DAILY;UNTIL=19970905", "1997-09-02", 20,
+			"1997-09-02 1997-09-03 1997-09-04 1997-09-05"},
+		{"weekly for 10 occurrences", "FREQ=WEEKLY;COUNT=10", "1997-09-02", 20,
+			"1997-09-02 1997-09-09 1997-09-16 1997-09-23 1997-09-30 1997-10-07 1997-10-14 1997-10-21 1997-10-28 1997-11-04"},
+		{"weekly on Tuesday and Thursday for five weeks", "FREQ=WEEKLY;COUNT=10;WKST=SU;BYDAY=TU,TH", "1997-09-02", 20,
+			"1997-09-02 1997-09-04 1997-09-09 1997-09-11 1997-09-16 1997-09-18 1997-09-23 1997-09-25 1997-09-30 1997-10-02"},
+		{"every other week on Monday, Wednesday and Friday", "FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;WKST=SU;BYDAY=MO,WE,FR", "1997-09-01", 50,
+			"1997-09-01 1997-09-03 1997-09-05 1997-09-15 1997-09-17 1997-09-19 1997-09-29 1997-10-01 1997-10-03 1997-10-13 1997-10-15 1997-10-17 " +
+				"1997-10-27 1997-10-29 1997-10-31 1997-11-10 1997-11-12 1997-11-14 1997-11-24 1997-11-26 1997-11-28 1997-12-08 1997-12-10 1997-12-12 1997-12-22"},
+		{"week start Monday", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", "1997-08-05", 20,
+			"1997-08-05 1997-08-10 1997-08-19 1997-08-24"},
+		{"week start Sunday", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", "1997-08-05", 20,
+			"1997-08-05 1997-08-17 1997-08-19 1997-08-31"},
+		{"monthly on the first Friday", "FREQ=MONTHLY;COUNT=10;BYDAY=1FR", "1997-09-05", 20,
+			"1997-09-05 1997-10-03 1997-11-07 1997-12-05 1998-01-02 1998-02-06 1998-03-06 1998-04-03 1998-05-01 1998-06-05"},
+		{"every other month on the first and last Sunday", "FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU", "1997-09-07", 20,
+			"1997-09-07 1997-09-28 1997-11-02 1997-11-30 1998-01-04 1998-01-25 1998-03-01 1998-03-29 1998-05-03 1998-05-31"},
+		{"monthly on the second-to-last Monday", "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO", "1997-09-22", 20,
+			"1997-09-22 1997-10-20 1997-11-17 1997-12-22 1998-01-19 1998-02-16"},
+		{"monthly on the third-to-last day", "FREQ=MONTHLY;BYMONTHDAY=-3", "1997-09-28", 6,
+			"1997-09-28 1997-10-29 1997-11-28 1997-12-29 1998-01-29 1998-02-26"},
+		{"monthly on the 2nd and 15th", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15", "1997-09-02", 20,
+			"1997-09-02 1997-09-15 1997-10-02 1997-10-15 1997-11-02 1997-11-15 1997-12-02 1997-12-15 1998-01-02 1998-01-15"},
+		{"monthly on the first and last day", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1", "1997-09-30", 20,
+			"1997-09-30 1997-10-01 1997-10-31 1997-11-01 1997-11-30 1997-12-01 1997-12-31 1998-01-01 1998-01-31 1998-02-01"},
+		{"every 18 months on the 10th to 15th", "FREQ=MONTHLY;INTERVAL=18;COUNT=10;BYMONTHDAY=10,11,12,13,14,15", "1997-09-10", 20,
+			"1997-09-10 1997-09-11 1997-09-12 1997-09-13 1997-09-14 1997-09-15 1999-03-10 1999-03-11 1999-03-12 1999-03-13"},
+		{"every Tuesday, every other month", "FREQ=MONTHLY;INTERVAL=2;BYDAY=TU", "1997-09-02", 18,
+			"1997-09-02 1997-09-09 1997-09-16 1997-09-23 1997-09-30 1997-11-04 1997-11-11 1997-11-18 1997-11-25 " +
+				"1998-01-06 1998-01-13 1998-01-20 1998-01-27 1998-03-03 1998-03-10 1998-03-17 1998-03-24 1998-03-31"},
+		{"every Friday the 13th", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", "1997-09-02", 5,
+			"1998-02-13 1998-03-13 1998-11-13 1999-08-13 2000-10-13"},
+		{"first Saturday after the first Sunday", "FREQ=MONTHLY;BYDAY=SA;BYMONTHDAY=7,8,9,10,11,12,13", "1997-09-13", 10,
+			"1997-09-13 1997-10-11 1997-11-08 1997-12-13 1998-01-10 1998-02-07 1998-03-07 1998-04-11 1998-05-09 1998-06-13"},
+		{"yearly in June and July", "FREQ=YEARLY;COUNT=10;BYMONTH=6,7", "1997-06-10", 20,
+			"1997-06-10 1997-07-10 1998-06-10 1998-07-10 1999-06-10 1999-07-10 2000-06-10 2000-07-10 2001-06-10 2001-07-10"},
+		{"every other year in January to March", "FREQ=YEARLY;INTERVAL=2;COUNT=10;BYMONTH=1,2,3", "1997-03-10", 20,
+			"1997-03-10 1999-01-10 1999-02-10 1999-03-10 2001-01-10 2001-02-10 2001-03-10 2003-01-10 2003-02-10 2003-03-10"},
+		{"every 20th Monday of the year", "FREQ=YEARLY;BYDAY=20MO", "1997-05-19", 3,
+			"1997-05-19 1998-05-18 1999-05-17"},
+		{"every Thursday in March", "FREQ=YEARLY;BYMONTH=3;BYDAY=TH", "1997-03-13", 11,
+			"1997-03-13 1997-03-20 1997-03-27 1998-03-05 1998-03-12 1998-03-19 1998-03-26 1999-03-04 1999-03-11 1999-03-18 1999-03-25"},
+		{"US presidential election day", "FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8", "1996-11-05", 3,
+			"1996-11-05 2000-11-07 2004-11-02"},
+
+		// Month ends: a day missing from a month is skipped, not moved
+		{"monthly on the 31st", "FREQ=MONTHLY;COUNT=4", "2023-01-31", 20,
+			"2023-01-31 2023-03-31 2023-05-31 2023-07-31"},
+		{"monthly on the 30th", "FREQ=MONTHLY", "2024-01-30", 3,
+			"2024-01-30 2024-03-30 2024-04-30"},
+		{"monthly on the last day", "FREQ=MONTHLY;BYMONTHDAY=-1", "2024-01-31", 4,
+			"2024-01-31 2024-02-29 2024-03-31 2024-04-30"},
+		{"monthly on the last Friday", "FREQ=MONTHLY;BYDAY=-1FR", "2024-01-26", 3,
+			"2024-01-26 2024-02-23 2024-03-29"},
+		{"yearly on February 29th", "FREQ=YEARLY;COUNT=3", "2024-02-29", 20,
+			"2024-02-29 2028-02-29 2032-02-29"},
+		{"yearly on the last day of February", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1", "2024-02-29", 3,
+			"2024-02-29 2025-02-28 2026-02-28"},
+
+		// Rules that never match end after maxPeriods periods
+		{"February 30th", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2024-01-01", 1, ""},
+		{"daily on April 31st", "FREQ=DAILY;BYMONTH=4;BYMONTHDAY=31", "2024-01-01", 1, ""},
+		{"dtstart after UNTIL", "FREQ=DAILY;UNTIL=20231231", "2024-01-01", 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(expand(t, tt.rule, at(tt.dtstart), tt.n), " ")
+			if got != tt.want {
+				t.Errorf("%s from %s:\n got %s\nwant %s", tt.rule, tt.dtstart, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDailyUntil checks the two spellings of "every day in January, for 3
+// years" and "daily until December 24, 1997" of RFC 5545.
+func TestDailyUntil(t *testing.T) {
+	tests := []struct {
+		rule        string
+		dtstart     string
+		count       int
+		first, last string
+	}{
+		{"FREQ=DAILY;UNTIL=19971224T000000Z", "1997-09-02", 113, "1997-09-02", "1997-12-23"},
+		{"FREQ=YEARLY;UNTIL=20000131T140000Z;BYMONTH=1;BYDAY=SU,MO,TU,WE,TH,FR,SA", "1998-01-01", 93, "1998-01-01", "2000-01-31"},
+		{"FREQ=DAILY;UNTIL=20000131T140000Z;BYMONTH=1", "1998-01-01", 93, "1998-01-01", "2000-01-31"},
+	}
+	for _, tt := range tests {
+		days := expand(t, tt.rule, at(tt.dtstart), 1000)
+		if len(days) != tt.count || days[0] != tt.first || days[len(days)-1] != tt.last {
+			t.Errorf("%s: %d occurrences from %s to %s, want %d from %s to %s",
+				tt.rule, len(days), days[0], days[len(days)-1], tt.count, tt.first, tt.last)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=MO,TH;COUNT=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := at("2024-03-04") // a Monday
+
+	tests := []struct {
+		after string
+		want  string // "" when the series has ended
+	}{
+		{"2024-03-01 00:00", "2024-03-04"},
+		{"2024-03-04 09:00", "2024-03-07"},
+		{"2024-03-08 12:00", "2024-03-11"},
+		{"2024-03-14 09:00", "2024-03-18"},
+		{"2024-03-18 09:00", ""},
+	}
+	for _, tt := range tests {
+		after, _ := time.Parse("2006-01-02 15:04", tt.after)
+		next, ok := rule.After(dtstart, after)
+		got := ""
+		if ok {
+			got = next.Format("2006-01-02")
+		}
+		if got != tt.want {
+			t.Errorf("After(%s) = %q, want %q", tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // canonical form, "" when invalid
+	}{
+		{"RRULE:FREQ=DAILY", "FREQ=DAILY"},
+		{"freq=weekly; byday=mo,we ;interval=1", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"FREQ=MONTHLY;BYDAY=-1FR,+2TU", "FREQ=MONTHLY;BYDAY=-1FR,2TU"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1,15;INTERVAL=3;COUNT=4", "FREQ=MONTHLY;INTERVAL=3;COUNT=4;BYMONTHDAY=-1,15"},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;UNTIL=20300101T000000Z", "FREQ=YEARLY;UNTIL=20300101T000000Z;BYMONTHDAY=29;BYMONTH=2"},
+		{"FREQ=WEEKLY;WKST=SU", "FREQ=WEEKLY;WKST=SU"},
+		{"", ""},
+		{"INTERVAL=2", ""},
+		{"FREQ=HOURLY", ""},
+		{"FREQ=DAILY;FREQ=WEEKLY", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=DAILY;COUNT=-1", ""},
+		{"FREQ=DAILY;COUNT=2;UNTIL=20240101", ""},
+		{"FREQ=DAILY;UNTIL=2024-01-01", ""},
+		{"FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"FREQ=MONTHLY;BYDAY=0MO", ""},
+		{"FREQ=MONTHLY;BYDAY=54MO", ""},
+		{"FREQ=MONTHLY;BYDAY=XX", ""},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"FREQ=YEARLY;BYMONTH=13", ""},
+		{"FREQ=DAILY;BYSETPOS=1", ""},
+		{"FREQ=DAILY;COUNT", ""},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		got := ""
+		if err == nil {
+			got = r.String()
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// internal/scheduler/scheduler.go
+package scheduler
+
+import (
+	"log"
+	"time"
+)
+
+// Job is a background task run on every tick of the scheduler.
+type Job struct {
+	Name string
+	Run  func() error
+}
+
+// Start runs each job right away and then every interval, each in its own
+// goroutine so a slow job does not hold up the others. Errors are logged and
+// the job runs again on the next tick.
+func Start(interval time.Duration, jobs ...Job) {
+	for _, job := range jobs {
+		go run(interval, job)
+	}
+}
+
+func run(interval time.Duration, job Job) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(); err != nil {
+			log.Printf("Scheduled job %q failed: %v", job.Name, err)
+		}
+		<-ticker.C
+	}
+}
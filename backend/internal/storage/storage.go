@@ -0,0 +1,49 @@
+// internal/storage/storage.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"timetracker/internal/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps file contents under slash separated keys.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object under key, returning ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+var Files Storage
+
+// Init sets up the backend chosen by STORAGE_DRIVER: "local" (the default)
+// or "s3".
+func Init() Storage {
+	cfg := config.AppConfig
+	switch cfg.StorageDriver {
+	case "", "local":
+		Files = &Local{Root: cfg.StoragePath}
+	case "s3":
+		if cfg.S3Bucket == "" || cfg.S3Endpoint == "" {
+			log.Fatal("S3 storage needs S3_ENDPOINT and S3_BUCKET")
+		}
+		Files = &S3{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		}
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q", cfg.StorageDriver)
+	}
+	return Files
+}
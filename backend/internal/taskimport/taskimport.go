@@ -0,0 +1,236 @@
+// Package taskimport reads task lists from CSV files and Markdown checklists.
+package taskimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Item is one task read from an import. Parent is the index of the item it
+// is nested under, or -1.
+type Item struct {
+	Line           int
+	Title          string
+	Description    string
+	Status         string // as written, empty when not given
+	Done           bool
+	Priority       string
+	DueDate        *time.Time
+	EstimatedHours float64
+	Tags           []string
+	Parent         int
+}
+
+var (
+	headingPattern  = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	checkboxPattern = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*\S)\s*$`)
+	fencePattern    = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// ParseMarkdown reads the "- [ ]" and "- [x]" checklist items of a Markdown
+// document, ticked items being done. Items indented under another item are
+// its subtasks, text indented under an item is its description, and the
+// headings an item sits under become its tags. Everything else is ignored.
+func ParseMarkdown(r io.Reader) ([]Item, error) {
+	var items []Item
+	var headings []string // by level, "" where a level is skipped
+
+	type open struct{ indent, index int }
+	var stack []open // items that later lines may nest under
+	inFence := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.ReplaceAll(scanner.Text(), "\t", "    ")
+
+		if fencePattern.MatchString(text) {
+			inFence = !inFence
+			continue
+		}
+		if inFence || strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(text); m != nil {
+			level := len(m[1])
+			for len(headings) < level {
+				headings = append(headings, "")
+			}
+			headings = append(headings[:level-1], strings.TrimSpace(m[2]))
+			stack = nil
+			continue
+		}
+
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		m := checkboxPattern.FindStringSubmatch(text)
+		if m == nil {
+			if len(stack) > 0 {
+				item := &items[stack[len(stack)-1].index]
+				if item.Description != "" {
+					item.Description += "\n"
+				}
+				item.Description += strings.TrimSpace(text)
+			}
+			continue
+		}
+
+		item := Item{
+			Line:   line,
+			Title:  m[3],
+			Done:   m[2] != " ",
+			Parent: -1,
+		}
+		if len(stack) > 0 {
+			item.Parent = stack[len(stack)-1].index
+		}
+		for _, h := range headings {
+			if h != "" {
+				item.Tags = append(item.Tags, h)
+			}
+		}
+
+		stack = append(stack, open{indent: len(m[1]), index: len(items)})
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("no checklist items found")
+	}
+	return items, nil
+}
+
+// csvColumns maps the accepted CSV headers to the fields they fill.
+var csvColumns = map[string]string{
+	"title":           "title",
+	"name":            "title",
+	"description":     "description",
+	"notes":           "description",
+	"status":          "status",
+	"done":            "done",
+	"completed":       "done",
+	"priority":        "priority",
+	"due_date":        "due_date",
+	"due":             "due_date",
+	"estimated_hours": "estimated_hours",
+	"estimate":        "estimated_hours",
+	"tags":            "tags",
+}
+
+// ParseCSV reads one task per row. The header row names the columns: title
+// is required; description, status, done, priority, due_date (YYYY-MM-DD),
+// estimated_hours and tags (comma or semicolon separated) are optional, and
+// other columns are ignored.
+func ParseCSV(r io.Reader) ([]Item, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+		if field, ok := csvColumns[name]; ok {
+			if _, dup := columns[field]; dup {
+				return nil, fmt.Errorf("line 1: more than one %s column", field)
+			}
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("line 1: a title column is required")
+	}
+
+	var items []Item
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		item := Item{
+			Line:        line,
+			Title:       field("title"),
+			Description: field("description"),
+			Status:      field("status"),
+			Priority:    field("priority"),
+			Parent:      -1,
+		}
+		if item.Title == "" {
+			return nil, fmt.Errorf("line %d: title is required", line)
+		}
+
+		switch strings.ToLower(field("done")) {
+		case "", "no", "false", "0", "n":
+		case "yes", "true", "1", "x", "y":
+			item.Done = true
+		default:
+			return nil, fmt.Errorf("line %d: invalid done value %q", line, field("done"))
+		}
+
+		if v := field("due_date"); v != "" {
+			d, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid due date %q", line, v)
+			}
+			item.DueDate = &d
+		}
+
+		if v := field("estimated_hours"); v != "" {
+			hours, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(hours) || math.IsInf(hours, 0) {
+				return nil, fmt.Errorf("line %d: invalid estimated hours %q", line, v)
+			}
+			item.EstimatedHours = hours
+		}
+
+		for _, tag := range strings.FieldsFunc(field("tags"), func(r rune) bool { return r == ',' || r == ';' }) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				item.Tags = append(item.Tags, tag)
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("no rows found")
+	}
+	return items, nil
+}
@@ -0,0 +1,177 @@
+package taskimport
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Item
+	}{
+		{
+			name:  "checklist",
+			input: "- [ ] Write tests\n* [x] Fix bug\n1. [X] Release\n",
+			want: []Item{
+				{Line: 1, Title: "Write tests", Parent: -1},
+				{Line: 2, Title: "Fix bug", Done: true, Parent: -1},
+				{Line: 3, Title: "Release", Done: true, Parent: -1},
+			},
+		},
+		{
+			name:  "nesting",
+			input: "- [ ] Parent\n  - [ ] Child\n    - [x] Grandchild\n  - [ ] Second child\n- [ ] Sibling\n\t- [ ] Tab child\n",
+			want: []Item{
+				{Line: 1, Title: "Parent", Parent: -1},
+				{Line: 2, Title: "Child", Parent: 0},
+				{Line: 3, Title: "Grandchild", Done: true, Parent: 1},
+				{Line: 4, Title: "Second child", Parent: 0},
+				{Line: 5, Title: "Sibling", Parent: -1},
+				{Line: 6, Title: "Tab child", Parent: 4},
+			},
+		},
+		{
+			name:  "headings become tags",
+			input: "# Backend\n- [ ] API\n## Auth ##\n- [ ] Login\n### \n# Frontend\n#### Forms\n- [ ] Signup\n",
+			want: []Item{
+				{Line: 2, Title: "API", Tags: []string{"Backend"}, Parent: -1},
+				{Line: 4, Title: "Login", Tags: []string{"Backend", "Auth"}, Parent: -1},
+				{Line: 8, Title: "Signup", Tags: []string{"Frontend", "Forms"}, Parent: -1},
+			},
+		},
+		{
+			name:  "heading ends nesting",
+			input: "- [ ] Parent\n# Later\n  - [ ] Not a child\n",
+			want: []Item{
+				{Line: 1, Title: "Parent", Parent: -1},
+				{Line: 3, Title: "Not a child", Tags: []string{"Later"}, Parent: -1},
+			},
+		},
+		{
+			name:  "descriptions",
+			input: "- [ ] Task\n  First line\n\n  Second line\n  - [ ] Subtask\n    Sub note\nNot indented\n- [ ] Other\n",
+			want: []Item{
+				{Line: 1, Title: "Task", Description: "First line\nSecond line", Parent: -1},
+				{Line: 5, Title: "Subtask", Description: "Sub note", Parent: 0},
+				{Line: 8, Title: "Other", Parent: -1},
+			},
+		},
+		{
+			name:  "fences are skipped",
+			input: "- [ ] Task\n```\n- [ ] In code\n# Not a heading\n```\n~~~go\n- [x] Also code\n~~~\n- [ ] After\n",
+			want: []Item{
+				{Line: 1, Title: "Task", Parent: -1},
+				{Line: 9, Title: "After", Parent: -1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMarkdown(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMarkdown() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownErrors(t *testing.T) {
+	for _, input := range []string{"", "# Title\nSome text\n- plain item\n", "```\n- [ ] In code\n```\n"} {
+		if _, err := ParseMarkdown(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseMarkdown(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	due := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []Item
+	}{
+		{
+			name:  "all columns",
+			input: "Title,Description,Status,Done,Priority,Due Date,Estimated-Hours,Tags,Owner\nWrite docs,For the API,in progress,no,high,2024-05-31,2.5,\"docs, api;backend\",ann\n",
+			want: []Item{{
+				Line:           2,
+				Title:          "Write docs",
+				Description:    "For the API",
+				Status:         "in progress",
+				Priority:       "high",
+				DueDate:        &due,
+				EstimatedHours: 2.5,
+				Tags:           []string{"docs", "api", "backend"},
+				Parent:         -1,
+			}},
+		},
+		{
+			name:  "aliases and byte order mark",
+			input: "\ufeffname,notes,completed,due,estimate\nA,note,x,,\nB,,0,,1\n",
+			want: []Item{
+				{Line: 2, Title: "A", Description: "note", Done: true, Parent: -1},
+				{Line: 3, Title: "B", EstimatedHours: 1, Parent: -1},
+			},
+		},
+		{
+			name:  "short rows",
+			input: "title,priority,tags\nOnly title\n",
+			want:  []Item{{Line: 2, Title: "Only title", Parent: -1}},
+		},
+		{
+			name:  "multi-line field",
+			input: "title,description\n\"Task\",\"line one\nline two\"\nNext,\n",
+			want: []Item{
+				{Line: 2, Title: "Task", Description: "line one\nline two", Parent: -1},
+				{Line: 4, Title: "Next", Parent: -1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCSV() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"empty", "", "file is empty"},
+		{"no title column", "description\nx\n", "line 1: a title column is required"},
+		{"duplicate column", "title,name\na,b\n", "line 1: more than one title column"},
+		{"no rows", "title\n", "no rows found"},
+		{"missing title", "title,priority\nA,low\n,high\n", "line 3: title is required"},
+		{"done", "title,done\nA,maybe\n", `line 2: invalid done value "maybe"`},
+		{"due date", "title,due\nA,31/05/2024\n", `line 2: invalid due date "31/05/2024"`},
+		{"estimate", "title,estimate\nA,two\n", `line 2: invalid estimated hours "two"`},
+		{"NaN estimate", "title,estimate\nA,NaN\n", `line 2: invalid estimated hours "NaN"`},
+		{"infinite estimate", "title,estimate\nA,+Inf\n", `line 2: invalid estimated hours "+Inf"`},
+		{"overflowing estimate", "title,estimate\nA,1e400\n", `line 2: invalid estimated hours "1e400"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tt.input))
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("ParseCSV() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
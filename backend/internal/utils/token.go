@@ -51,3 +51,15 @@ func GetUserID(c *gin.Context) uint {
 	}
 	return userID.(uint)
 }
+
+func GetWorkspaceID(c *gin.Context) uint {
+	workspaceID, exists := c.Get("workspace_id")
+	if !exists {
+		return 0
+	}
+	return workspaceID.(uint)
+}
+
+func GetWorkspaceRole(c *gin.Context) string {
+	return c.GetString("workspace_role")
+}
@@ -10,7 +10,7 @@ interface TaskResponse {
   title: string;
   description: string;
   status: string;
-  tags: string[];
+  tags: { ID: number; name: string; color: string }[];
   project_id: number;
   user_id: number;
   time_entries: any[];
@@ -21,7 +21,7 @@ const transformResponse = (data: TaskResponse): Task => ({
   title: data.title,
   description: data.description,
   status: data.status,
-  tags: Array.isArray(data.tags) ? data.tags : [],
+  tags: Array.isArray(data.tags) ? data.tags.map(tag => tag.name) : [],
   projectId: data.project_id,
   userId: data.user_id
 });